@@ -6,11 +6,16 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"regexp"
 	"strconv"
+	"strings"
 	"syscall"
+	"time"
+	// Subscriber timezones are resolved without the system database
+	_ "time/tzdata"
 
 	cloudflarebp "github.com/DaRealFreak/cloudflare-bp-go"
 	"github.com/go-faster/errors"
@@ -18,13 +23,18 @@ import (
 	"github.com/freebies-telegram-bot/internal/bot"
 	"github.com/freebies-telegram-bot/internal/db"
 	"github.com/freebies-telegram-bot/internal/fetchers"
+	"github.com/freebies-telegram-bot/internal/rules"
 	"github.com/freebies-telegram-bot/internal/worker"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	_ "modernc.org/sqlite"
 )
 
-const WeeklyCron = "0 0 0 * * 1"
+const (
+	WeeklyCron          = "0 0 0 * * 1"
+	EveryMinuteCron     = "0 * * * * *"
+	RulesReloadInterval = time.Minute
+)
 
 var markdownRe = regexp.MustCompile(`([!\(\).])`)
 
@@ -34,8 +44,11 @@ func sendMessage(bot *bot.Bot, chatId int, message string) error {
 	return bot.SendMsgWithMarkdown(int64(chatId), message)
 }
 
-func setupServer(bot *bot.Bot, storage *db.SqliteStorage) {
+func setupServer(bot *bot.Bot, storage *db.SqliteStorage, webhook *url.URL, webhookSecret string) {
 	http.Handle("/metrics", promhttp.Handler())
+	if webhook != nil {
+		http.Handle(webhook.Path, bot.WebhookHandler(webhookSecret))
+	}
 	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintf(w, "OK")
 	})
@@ -101,6 +114,45 @@ func setupServer(bot *bot.Bot, storage *db.SqliteStorage) {
 	}
 }
 
+// setupWebhook reads the public URL of the webhook, the updates are received
+// by the HTTP server at its path
+func setupWebhook() (*url.URL, string, error) {
+	webhookUrl, ok := os.LookupEnv("WEBHOOK_URL")
+	if !ok || webhookUrl == "" {
+		return nil, "", nil
+	}
+	webhook, err := url.Parse(webhookUrl)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "invalid WEBHOOK_URL")
+	}
+	if webhook.Scheme != "https" || webhook.Path == "" || webhook.Path == "/" {
+		return nil, "", errors.New("WEBHOOK_URL must be an https URL with a path")
+	}
+	secret, _ := os.LookupEnv("WEBHOOK_SECRET")
+	if secret == "" {
+		return nil, "", errors.New("WEBHOOK_SECRET is required with WEBHOOK_URL")
+	}
+	return webhook, secret, nil
+}
+
+// setupOperators reads the comma separated ids of the operator chats
+func setupOperators() ([]int64, error) {
+	value, _ := os.LookupEnv("ADMIN_CHAT_IDS")
+	operators := []int64{}
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		chatId, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid ADMIN_CHAT_IDS")
+		}
+		operators = append(operators, chatId)
+	}
+	return operators, nil
+}
+
 func setupDB(dbPath string) (*sql.DB, error) {
 	conn, err := sql.Open("sqlite", "file:"+dbPath+"/db.sqlite3?_pragma=journal_mode(wal)&_pragma=busy_timeout(10000)")
 	if err != nil {
@@ -112,6 +164,38 @@ func setupDB(dbPath string) (*sql.DB, error) {
 	return conn, nil
 }
 
+func setupSources(httpClient *http.Client, storage *db.SqliteStorage) (*fetchers.Registry, error) {
+	maxPages := fetchers.DefaultMaxPages
+	if value, ok := os.LookupEnv("FREE_GAME_FINDINGS_MAX_PAGES"); ok {
+		var err error
+		maxPages, err = strconv.Atoi(value)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid FREE_GAME_FINDINGS_MAX_PAGES")
+		}
+	}
+
+	var freeGameFindings fetchers.Fetcher = fetchers.NewRedditFetcher(fetchers.FREE_GAME_FINDINGS_JSON_URL, httpClient, storage).WithMaxPages(maxPages)
+	// Scraping of old.reddit.com is kept for when the JSON listing is blocked
+	if mode, _ := os.LookupEnv("FREE_GAME_FINDINGS_MODE"); mode == "html" {
+		freeGameFindings = fetchers.NewFreeGameFindingsFetcher(fetchers.FREE_GAME_FINDINGS_URL, httpClient, storage).WithMaxPages(maxPages)
+	}
+
+	sources := fetchers.NewRegistry()
+	err := sources.Register("freegamefindings", freeGameFindings, fetchers.DefaultSourceTimeout)
+	if err != nil {
+		return nil, err
+	}
+	err = sources.Register("epicgames", fetchers.NewEpicGamesFetcher(fetchers.EPIC_GAMES_URL, httpClient, storage), fetchers.DefaultSourceTimeout)
+	if err != nil {
+		return nil, err
+	}
+	err = sources.Register("pikabu", fetchers.NewPikabuFetcher(fetchers.PIKABU_URL, httpClient, storage), fetchers.DefaultSourceTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return sources, nil
+}
+
 func main() {
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
 	dbPath, ok := os.LookupEnv("DB_PATH")
@@ -130,28 +214,80 @@ func main() {
 
 	storage := db.NewStorage(conn)
 
-	bot, err := bot.NewBot(storage, fetchers.NewFreeGameFindingsFetcher(fetchers.FREE_GAME_FINDINGS_URL, httpClient, storage))
+	sources, err := setupSources(httpClient, storage)
 	if err != nil {
 		log.Panic(err)
 	}
 
+	// Without RULES_PATH the built-in rules are used
+	rulesPath, _ := os.LookupEnv("RULES_PATH")
+	linkRules, err := rules.NewEngine(rulesPath)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	// Without CHANNELS_PATH nothing is broadcast
+	channelsPath, _ := os.LookupEnv("CHANNELS_PATH")
+	channels, err := bot.LoadChannels(channelsPath)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	// Without WEBHOOK_URL the updates are polled
+	webhook, webhookSecret, err := setupWebhook()
+	if err != nil {
+		log.Panic(err)
+	}
+
+	// Without ADMIN_CHAT_IDS nobody can run the operator commands
+	operators, err := setupOperators()
+	if err != nil {
+		log.Panic(err)
+	}
+
+	bot, err := bot.NewBot(storage, sources, linkRules, channels, operators)
+	if err != nil {
+		log.Panic(err)
+	}
+	if err = bot.SetCommands(); err != nil {
+		log.Println(err)
+	}
+
 	logsCleaner, err := worker.NewLogsCleaner(storage)
 	if err != nil {
 		log.Panic(err)
 	}
 
+	digestScheduler, err := worker.NewDigestScheduler(bot)
+	if err != nil {
+		log.Panic(err)
+	}
+
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
-	go setupServer(bot, storage)
+	go setupServer(bot, storage, webhook, webhookSecret)
+	go linkRules.Watch(ctx, RulesReloadInterval)
 	go bot.WatchNewPosts(ctx)
+	go bot.WatchOutbox(ctx)
 
 	if err = logsCleaner.Start(WeeklyCron); err != nil {
 		log.Panic(err)
 	}
 
-	bot.Run()
+	if err = digestScheduler.Start(EveryMinuteCron); err != nil {
+		log.Panic(err)
+	}
+
+	if webhook != nil {
+		if err = bot.RunWebhook(webhook.String(), webhookSecret); err != nil {
+			log.Panic(err)
+		}
+	} else {
+		bot.Run()
+	}
 
 	logsCleaner.Stop(ctx)
+	digestScheduler.Stop(ctx)
 
 }
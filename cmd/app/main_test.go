@@ -18,6 +18,7 @@ import (
 	"github.com/freebies-telegram-bot/internal/bot"
 	"github.com/freebies-telegram-bot/internal/db"
 	"github.com/freebies-telegram-bot/internal/fetchers"
+	"github.com/freebies-telegram-bot/internal/rules"
 	"github.com/freebies-telegram-bot/internal/worker"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -79,7 +80,18 @@ func TestWatchNewPosts(t *testing.T) {
 
 	mockClient := &http.Client{}
 
-	tgBot, err := bot.NewBot(storage, fetchers.NewFreeGameFindingsFetcher(httpServer.URL, mockClient, storage))
+	sources := fetchers.NewRegistry()
+	err := sources.Register("freegamefindings", fetchers.NewFreeGameFindingsFetcher(httpServer.URL, mockClient, storage), time.Second)
+	if err != nil {
+		log.Fatalf("test setup failed: %s", err.Error())
+	}
+
+	linkRules, err := rules.NewEngine("")
+	if err != nil {
+		log.Fatalf("test setup failed: %s", err.Error())
+	}
+
+	tgBot, err := bot.NewBot(storage, sources, linkRules, nil, nil)
 	if err != nil {
 		log.Fatalf("test setup failed: %s", err.Error())
 	}
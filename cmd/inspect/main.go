@@ -1,13 +1,16 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	cloudflarebp "github.com/DaRealFreak/cloudflare-bp-go"
 	"github.com/alexflint/go-arg"
+	"github.com/freebies-telegram-bot/internal/db"
 	"github.com/freebies-telegram-bot/internal/fetchers"
 )
 
@@ -54,6 +57,10 @@ func (is *InspectStorage) StoreBody(fetchId int64, body string) error {
 	return nil
 }
 
+func (is *InspectStorage) AppendBody(fetchId int64, body string) error {
+	return nil
+}
+
 func (is *InspectStorage) StoreError(fetchId int64, errorStr string) error {
 	return nil
 }
@@ -66,7 +73,11 @@ func (is *InspectStorage) DeleteFetchesOlderThan(deadline time.Time) (int64, err
 	return 0, nil
 }
 
-func (is *InspectStorage) StorePost(fetch_id int64, link string, title string, postedAt time.Time) error {
+func (is *InspectStorage) StorePost(post db.Post) error {
+	return nil
+}
+
+func (is *InspectStorage) StorePromotion(post db.Post) error {
 	return nil
 }
 
@@ -82,9 +93,12 @@ func main() {
 
 	storage := &InspectStorage{}
 
-	fetcher := fetchers.NewFreeGameFindingsFetcher(args.Source, httpClient, storage)
+	var fetcher fetchers.Fetcher = fetchers.NewFreeGameFindingsFetcher(args.Source, httpClient, storage)
+	if strings.HasSuffix(args.Source, ".json") {
+		fetcher = fetchers.NewRedditFetcher(args.Source, httpClient, storage)
+	}
 
-	fetch, err := fetcher.Fetch(args.Since.Time)
+	fetch, err := fetcher.Fetch(context.Background(), args.Since.Time)
 	if err != nil {
 		log.Fatalf("%s", err.Error())
 	}
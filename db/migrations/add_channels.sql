@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS `channels` (
+    `chat_id` INTEGER PRIMARY KEY,
+    'last_post' TEXT NOT NULL
+);
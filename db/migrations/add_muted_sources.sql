@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS `muted_sources` (
+    `name` TEXT PRIMARY KEY,
+    'created_at' DATETIME DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS `outbox` (
+    `id` INTEGER PRIMARY KEY AUTOINCREMENT,
+    `chat_id` INTEGER NOT NULL,
+    'text' TEXT NOT NULL,
+    'parse_mode' TEXT NOT NULL DEFAULT '',
+    'disable_web_page_preview' BOOLEAN NOT NULL DEFAULT 0,
+    'reply_markup' TEXT NULL,
+    'status' TEXT NOT NULL DEFAULT 'pending',
+    'attempts' INTEGER NOT NULL DEFAULT 0,
+    'next_attempt_at' DATETIME NOT NULL,
+    'last_error' TEXT NULL,
+    'message_id' INTEGER NULL,
+    'sent_at' DATETIME NULL,
+    'created_at' DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS `outbox_pending` ON `outbox` (`status`, `next_attempt_at`);
+CREATE INDEX IF NOT EXISTS `outbox_chat_pending` ON `outbox` (`chat_id`, `status`);
+
+CREATE TABLE IF NOT EXISTS `outbox_posts` (
+    `outbox_id` INTEGER NOT NULL,
+    'post_id' INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS `outbox_posts_outbox_id` ON `outbox_posts` (`outbox_id`);
+CREATE INDEX IF NOT EXISTS `outbox_posts_post_id` ON `outbox_posts` (`post_id`);
@@ -0,0 +1,3 @@
+-- Replicas claim the pending messages before sending them
+ALTER TABLE `outbox` ADD COLUMN 'claimed_by' TEXT NULL;
+ALTER TABLE `outbox` ADD COLUMN 'claimed_at' DATETIME NULL;
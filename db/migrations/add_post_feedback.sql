@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS `post_feedback` (
+    `chat_id` INTEGER NOT NULL,
+    'post_id' INTEGER NOT NULL,
+    'link' TEXT NOT NULL,
+    'title' TEXT NULL,
+    'status' TEXT NOT NULL,
+    'created_at' DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(`chat_id`, 'post_id')
+);
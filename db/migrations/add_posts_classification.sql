@@ -0,0 +1,2 @@
+ALTER TABLE `posts` ADD COLUMN 'platform' TEXT NULL;
+ALTER TABLE `posts` ADD COLUMN 'deal_type' TEXT NULL;
@@ -0,0 +1 @@
+ALTER TABLE `posts` ADD COLUMN 'expires_at' DATETIME NULL;
@@ -0,0 +1,23 @@
+-- Full text index of the titles and the links of the posts for /search
+CREATE VIRTUAL TABLE IF NOT EXISTS `posts_fts` USING fts5(
+    title,
+    link,
+    content='posts',
+    content_rowid='id'
+);
+
+CREATE TRIGGER IF NOT EXISTS `posts_fts_insert` AFTER INSERT ON `posts` BEGIN
+    INSERT INTO posts_fts(rowid, title, link) VALUES (new.id, new.title, new.link);
+END;
+
+CREATE TRIGGER IF NOT EXISTS `posts_fts_delete` AFTER DELETE ON `posts` BEGIN
+    INSERT INTO posts_fts(posts_fts, rowid, title, link) VALUES ('delete', old.id, old.title, old.link);
+END;
+
+CREATE TRIGGER IF NOT EXISTS `posts_fts_update` AFTER UPDATE OF title, link ON `posts` BEGIN
+    INSERT INTO posts_fts(posts_fts, rowid, title, link) VALUES ('delete', old.id, old.title, old.link);
+    INSERT INTO posts_fts(rowid, title, link) VALUES (new.id, new.title, new.link);
+END;
+
+-- Indexes the posts stored before
+INSERT INTO posts_fts(posts_fts) VALUES ('rebuild');
@@ -0,0 +1,6 @@
+ALTER TABLE `posts` ADD COLUMN 'flair' TEXT NULL;
+ALTER TABLE `posts` ADD COLUMN 'domain' TEXT NULL;
+ALTER TABLE `posts` ADD COLUMN 'author' TEXT NULL;
+ALTER TABLE `posts` ADD COLUMN 'score' INTEGER NULL;
+ALTER TABLE `posts` ADD COLUMN 'comments' INTEGER NULL;
+ALTER TABLE `posts` ADD COLUMN 'permalink' TEXT NULL;
@@ -0,0 +1 @@
+ALTER TABLE `posts` ADD COLUMN 'source' TEXT NULL;
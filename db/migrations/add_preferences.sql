@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS `subscriber_preferences` (
+    `chat_id` INTEGER PRIMARY KEY,
+    'hidden_platforms' TEXT NOT NULL DEFAULT ''
+);
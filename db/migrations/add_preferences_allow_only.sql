@@ -0,0 +1 @@
+ALTER TABLE `subscriber_preferences` ADD COLUMN 'allow_only' BOOLEAN NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE `subscriber_preferences` ADD COLUMN 'batch_links' BOOLEAN NOT NULL DEFAULT 0;
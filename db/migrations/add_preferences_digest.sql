@@ -0,0 +1,4 @@
+ALTER TABLE `subscriber_preferences` ADD COLUMN 'delivery_mode' TEXT NOT NULL DEFAULT 'instant';
+ALTER TABLE `subscriber_preferences` ADD COLUMN 'digest_time' INTEGER NOT NULL DEFAULT 540;
+ALTER TABLE `subscriber_preferences` ADD COLUMN 'digest_weekday' INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE `subscriber_preferences` ADD COLUMN 'last_digest_at' DATETIME NULL;
@@ -0,0 +1 @@
+ALTER TABLE `subscriber_preferences` ADD COLUMN 'language' TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+ALTER TABLE `subscriber_preferences` ADD COLUMN 'timezone' TEXT NOT NULL DEFAULT 'UTC';
+ALTER TABLE `subscriber_preferences` ADD COLUMN 'quiet_start' INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE `subscriber_preferences` ADD COLUMN 'quiet_end' INTEGER NOT NULL DEFAULT 0;
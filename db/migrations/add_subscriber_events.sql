@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS `subscriber_events` (
+    `id` INTEGER PRIMARY KEY AUTOINCREMENT,
+    `chat_id` INTEGER NOT NULL,
+    'event' TEXT NOT NULL,
+    'reason' TEXT NULL,
+    'created_at' DATETIME DEFAULT CURRENT_TIMESTAMP
+);
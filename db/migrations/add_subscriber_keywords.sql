@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS `subscriber_keywords` (
+    `chat_id` INTEGER NOT NULL,
+    'word' TEXT NOT NULL,
+    UNIQUE(`chat_id`, 'word')
+);
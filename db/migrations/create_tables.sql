@@ -17,11 +17,120 @@ CREATE TABLE IF NOT EXISTS `posts` (
     'link' TEXT NOT NULL UNIQUE,
     'title' TEXT NULL,
     'posted_at' DATETIME NOT NULL,
-    'created_at' DATETIME DEFAULT CURRENT_TIMESTAMP
+    'expires_at' DATETIME NULL,
+    'created_at' DATETIME DEFAULT CURRENT_TIMESTAMP,
+    'flair' TEXT NULL,
+    'domain' TEXT NULL,
+    'author' TEXT NULL,
+    'score' INTEGER NULL,
+    'comments' INTEGER NULL,
+    'permalink' TEXT NULL,
+    'platform' TEXT NULL,
+    'deal_type' TEXT NULL,
+    'source' TEXT NULL
 );
 
 CREATE TABLE IF NOT EXISTS `delivered_posts` (
     `post_id` INTEGER NOT NULL,
     'receiver' INTEGER NOT NULL,
     'delivery_date' DATETIME DEFAULT CURRENT_TIMESTAMP
-);
\ No newline at end of file
+);
+
+CREATE TABLE IF NOT EXISTS `subscriber_preferences` (
+    `chat_id` INTEGER PRIMARY KEY,
+    'hidden_platforms' TEXT NOT NULL DEFAULT '',
+    'allow_only' BOOLEAN NOT NULL DEFAULT 0,
+    'batch_links' BOOLEAN NOT NULL DEFAULT 0,
+    'delivery_mode' TEXT NOT NULL DEFAULT 'instant',
+    'digest_time' INTEGER NOT NULL DEFAULT 540,
+    'digest_weekday' INTEGER NOT NULL DEFAULT 1,
+    'last_digest_at' DATETIME NULL,
+    'timezone' TEXT NOT NULL DEFAULT 'UTC',
+    'quiet_start' INTEGER NOT NULL DEFAULT 0,
+    'quiet_end' INTEGER NOT NULL DEFAULT 0,
+    'language' TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS `subscriber_keywords` (
+    `chat_id` INTEGER NOT NULL,
+    'word' TEXT NOT NULL,
+    UNIQUE(`chat_id`, 'word')
+);
+
+CREATE TABLE IF NOT EXISTS `post_feedback` (
+    `chat_id` INTEGER NOT NULL,
+    'post_id' INTEGER NOT NULL,
+    'link' TEXT NOT NULL,
+    'title' TEXT NULL,
+    'status' TEXT NOT NULL,
+    'created_at' DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(`chat_id`, 'post_id')
+);
+
+CREATE TABLE IF NOT EXISTS `subscriber_events` (
+    `id` INTEGER PRIMARY KEY AUTOINCREMENT,
+    `chat_id` INTEGER NOT NULL,
+    'event' TEXT NOT NULL,
+    'reason' TEXT NULL,
+    'created_at' DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS `outbox` (
+    `id` INTEGER PRIMARY KEY AUTOINCREMENT,
+    `chat_id` INTEGER NOT NULL,
+    'text' TEXT NOT NULL,
+    'parse_mode' TEXT NOT NULL DEFAULT '',
+    'disable_web_page_preview' BOOLEAN NOT NULL DEFAULT 0,
+    'reply_markup' TEXT NULL,
+    'status' TEXT NOT NULL DEFAULT 'pending',
+    'attempts' INTEGER NOT NULL DEFAULT 0,
+    'next_attempt_at' DATETIME NOT NULL,
+    'last_error' TEXT NULL,
+    'message_id' INTEGER NULL,
+    'sent_at' DATETIME NULL,
+    'claimed_by' TEXT NULL,
+    'claimed_at' DATETIME NULL,
+    'created_at' DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS `outbox_pending` ON `outbox` (`status`, `next_attempt_at`);
+CREATE INDEX IF NOT EXISTS `outbox_chat_pending` ON `outbox` (`chat_id`, `status`);
+
+CREATE TABLE IF NOT EXISTS `outbox_posts` (
+    `outbox_id` INTEGER NOT NULL,
+    'post_id' INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS `outbox_posts_outbox_id` ON `outbox_posts` (`outbox_id`);
+CREATE INDEX IF NOT EXISTS `outbox_posts_post_id` ON `outbox_posts` (`post_id`);
+
+CREATE TABLE IF NOT EXISTS `muted_sources` (
+    `name` TEXT PRIMARY KEY,
+    'created_at' DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Full text index of the titles and the links of the posts for /search
+CREATE VIRTUAL TABLE IF NOT EXISTS `posts_fts` USING fts5(
+    title,
+    link,
+    content='posts',
+    content_rowid='id'
+);
+
+CREATE TRIGGER IF NOT EXISTS `posts_fts_insert` AFTER INSERT ON `posts` BEGIN
+    INSERT INTO posts_fts(rowid, title, link) VALUES (new.id, new.title, new.link);
+END;
+
+CREATE TRIGGER IF NOT EXISTS `posts_fts_delete` AFTER DELETE ON `posts` BEGIN
+    INSERT INTO posts_fts(posts_fts, rowid, title, link) VALUES ('delete', old.id, old.title, old.link);
+END;
+
+CREATE TRIGGER IF NOT EXISTS `posts_fts_update` AFTER UPDATE OF title, link ON `posts` BEGIN
+    INSERT INTO posts_fts(posts_fts, rowid, title, link) VALUES ('delete', old.id, old.title, old.link);
+    INSERT INTO posts_fts(rowid, title, link) VALUES (new.id, new.title, new.link);
+END;
+
+CREATE TABLE IF NOT EXISTS `channels` (
+    `chat_id` INTEGER PRIMARY KEY,
+    'last_post' TEXT NOT NULL
+);
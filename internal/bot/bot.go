@@ -8,12 +8,17 @@ import (
 	"fmt"
 	"log"
 	"math/rand"
+	"net/url"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/freebies-telegram-bot/internal/db"
 	"github.com/freebies-telegram-bot/internal/fetchers"
+	"github.com/freebies-telegram-bot/internal/locale"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
@@ -36,6 +41,19 @@ var (
 		Name: "game_freebies_current_subscribers",
 		Help: "The current number of subscribers",
 	})
+	sourceErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "game_freebies_source_errors",
+		Help: "The number of failed fetches per source",
+	}, []string{"source"})
+	sendQueueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "game_freebies_send_queue_depth",
+		Help: "The number of messages waiting to be sent to Telegram",
+	})
+	sendLatency = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "game_freebies_send_latency_seconds",
+		Help:    "The time from queueing a message until Telegram has answered",
+		Buckets: prometheus.ExponentialBuckets(0.05, 2, 12),
+	})
 )
 
 var (
@@ -45,28 +63,98 @@ var (
 	rnd = rand.New(rand.NewSource(time.Now().UnixNano()))
 )
 
-type LinksFetcher interface {
-	Fetch(time.Time) (fetchers.Fetch, error)
+const (
+	// Chats get a single digest instead of a message per link above this
+	MaxSeparateLinks = 5
+	// Stored posts are out of date when no fetch has succeeded for this long
+	StalePostsAge = 15 * time.Minute
+)
+
+type LinksSources interface {
+	Sources() []fetchers.Source
+}
+
+// LinkRules decide which links are delivered to anyone
+type LinkRules interface {
+	IsAllowed(link fetchers.Link) bool
+	CountMatches(links []fetchers.Link)
 }
 
 type BotStorage interface {
 	GetPostByLink(link string) (db.Post, error)
-	StoreDeliveredPost(postId, receiver int64) error
+	GetPost(id int64) (db.Post, error)
+	SearchPostsHistory(text string) ([]db.Post, error)
+	StoreFeedback(chatId int64, post db.Post, status string) error
+	DeleteFeedback(chatId, postId int64) error
+	GetFeedback(chatId, postId int64) (db.Feedback, error)
+	ReadFeedbacks(chatId int64, status string) ([]db.Feedback, error)
 	GetDeliveredPost(postId, receiver int64) (db.DeliveredPost, error)
+	EnqueueMessages(messages []db.OutboxMessage, now time.Time) error
+	ClaimPendingMessages(claimant string, now time.Time, limit int) ([]db.OutboxMessage, error)
+	MarkMessageSent(id int64, messageId int, sentAt time.Time) error
+	RetryMessage(id int64, nextAttemptAt time.Time, errorStr string) error
+	FailMessage(id int64, errorStr string) error
+	IsPostQueued(postId, chatId int64) (bool, error)
+	ReadStats(sinceTime time.Time) (db.Stats, error)
+	ReadSubscriberStats(sinceTime time.Time) (db.SubscriberStats, error)
+	GetLastFetch() (db.Fetch, error)
+	GetLastFetchError() (db.Fetch, error)
+	ReadMutedSources() ([]string, error)
+	MuteSource(name string) error
+	UnmuteSource(name string) error
 	StoreSubscriber(chatId int64, sinceTime time.Time) error
 	UpdateLastPost(chatId int64, sinceTime time.Time) error
+	ReadChannelsLastPost() (map[int64]time.Time, error)
+	UpdateChannelLastPost(chatId int64, sinceTime time.Time) error
 	DeleteSubscriber(chatId int) error
+	StoreSubscriberEvent(chatId int64, event, reason string) error
 	ReadSubscribers() ([]db.Subscriber, error)
 	GetSubscriber(chatId int) (db.Subscriber, error)
+	GetPreferences(chatId int64) (db.Preferences, error)
+	UpdateHiddenPlatforms(chatId int64, platforms []string) error
+	UpdateAllowOnly(chatId int64, allowOnly bool) error
+	StoreKeyword(chatId int64, word string) error
+	DeleteKeyword(chatId int64, word string) (bool, error)
+	UpdateBatchLinks(chatId int64, batchLinks bool) error
+	UpdateLanguage(chatId int64, language string) error
+	MigrateChat(fromChatId, toChatId int64) error
+	UpdateTimezone(chatId int64, timezone string) error
+	UpdateQuietHours(chatId int64, start, end int) error
+	UpdateDigest(chatId int64, mode string, digestTime int, weekday time.Weekday, now time.Time) error
+	UpdateLastDigest(chatId int64, sentAt time.Time) error
+	ReadPostsSince(sinceTime time.Time) ([]db.Post, error)
+	UpdatePostsSource(fetchId int64, source string) error
+	ReadFetchPosts(fetchId int64) ([]db.Post, error)
+	SearchPosts(text string, sinceTime, now time.Time, limit int) ([]db.Post, error)
 }
 
 type Bot struct {
-	botApi  *tgbotapi.BotAPI
-	storage BotStorage
-	links   LinksFetcher
+	botApi   *tgbotapi.BotAPI
+	storage  BotStorage
+	sources  LinksSources
+	rules    LinkRules
+	channels []Channel
+	// Chats allowed to run the operator commands
+	operators []int64
+
+	inlineCache      *inlineCache
+	admins           *adminCache
+	disabledChannels *disabledChannels
+	sender           *sender
+	router           *commandRouter
+	broadcasts       *broadcasts
+	fetchNow         chan struct{}
+	// Updates received by the webhook
+	webhookUpdates chan tgbotapi.Update
+
+	// Unix nanoseconds of the last fetch where a source succeeded
+	lastFetchAt atomic.Int64
+	// Serializes the fetches of stale posts, lastRefreshAt is the last one
+	refreshMu     sync.Mutex
+	lastRefreshAt time.Time
 }
 
-func NewBot(storage BotStorage, links LinksFetcher) (*Bot, error) {
+func NewBot(storage BotStorage, sources LinksSources, rules LinkRules, channels []Channel, operators []int64) (*Bot, error) {
 	bot, err := tgbotapi.NewBotAPI(ApiToken)
 	if err != nil {
 		return nil, err
@@ -77,74 +165,102 @@ func NewBot(storage BotStorage, links LinksFetcher) (*Bot, error) {
 	log.Printf("Authorized on account %s", bot.Self.UserName)
 
 	return &Bot{
-		botApi:  bot,
-		storage: storage,
-		links:   links,
+		botApi:    bot,
+		storage:   storage,
+		sources:   sources,
+		rules:     rules,
+		channels:  channels,
+		operators: operators,
+
+		inlineCache:      newInlineCache(),
+		admins:           newAdminCache(),
+		disabledChannels: newDisabledChannels(),
+		sender:           newSender(bot.Send, newSendLimiter(GlobalSendRate, GlobalSendBurst), SendWorkers),
+		router:           newCommandRouter(commandTable()),
+		broadcasts:       newBroadcasts(),
+		fetchNow:         make(chan struct{}, 1),
+
+		webhookUpdates: make(chan tgbotapi.Update, WebhookQueueSize),
 	}, nil
 }
 
+// Run gets the updates by long polling, the webhook has to be deleted
+// for it to work
 func (b *Bot) Run() {
+	if _, err := b.botApi.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+		log.Println(err)
+	}
+
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60 * 5
 
-	updates := b.botApi.GetUpdatesChan(u)
+	b.dispatch(b.botApi.GetUpdatesChan(u))
+}
 
+// dispatch handles the updates, whether they come from polling or from the
+// webhook. Chats are served concurrently, so a chat waiting for its replies
+// doesn't hold up the others, the updates of a chat in the order they came.
+func (b *Bot) dispatch(updates <-chan tgbotapi.Update) {
+	chats := newChatQueues(b.handleUpdate)
 	for update := range updates {
-		if update.Message == nil { // ignore any non-Message Updates
-			continue
-		}
-		chatID := int64(update.Message.Chat.ID)
+		chats.push(updateChatId(update), update)
+	}
+}
 
-		now := time.Now().UTC().Truncate(24 * time.Hour)
+func (b *Bot) handleUpdate(update tgbotapi.Update) {
+	if update.CallbackQuery != nil {
+		b.handleCallback(update.CallbackQuery)
+		return
+	}
+	if update.InlineQuery != nil {
+		b.handleInlineQuery(update.InlineQuery)
+		return
+	}
+	if update.Message == nil { // ignore any non-Message Updates
+		return
+	}
+	chatID := int64(update.Message.Chat.ID)
 
-		switch update.Message.Command() {
-		case "start":
-			err := b.storage.StoreSubscriber(chatID, now)
-			if err != nil {
-				log.Println(err)
-			}
+	if update.Message.MigrateToChatID != 0 {
+		b.migrateChat(chatID, update.Message.MigrateToChatID)
+		return
+	}
+	if b.isForOtherBot(update.Message) {
+		return
+	}
+	b.detectLanguage(chatID, update.Message.From)
 
-			b.SendMsgWithMarkdown(update.Message.Chat.ID, "Hey\\! I'll be posting new freebies from steam community on pikabu\\.ru\\. Type _*/*_ to see the list of commands\\. 🙂")
-			b.SendPostsToUser(update.Message.Chat.ID, 0)
-
-		case "today":
-			b.SendPostsToUser(update.Message.Chat.ID, 1)
-		case "yesterday":
-			b.SendPostsToUser(update.Message.Chat.ID, 2)
-		case "week":
-			b.SendPostsToUser(update.Message.Chat.ID, 8)
-		case "month":
-			b.SendPostsToUser(update.Message.Chat.ID, 31)
-		case "receive":
-			_, err := b.storage.GetSubscriber(int(chatID))
-			if err != nil && !errors.Is(err, sql.ErrNoRows) {
-				log.Println(err)
-			} else if !errors.Is(err, sql.ErrNoRows) {
-				err = b.storage.DeleteSubscriber(int(chatID))
-				if err != nil {
-					log.Println(err)
-				}
-				err = b.SendMsg(update.Message.Chat.ID, "I won't be posting new freebies anymore. 😐")
-				if err != nil {
-					log.Println(err)
-				}
-			} else {
-				err = b.storage.StoreSubscriber(chatID, now)
-				if err != nil {
-					log.Println(err)
-				}
-				err = b.SendMsg(update.Message.Chat.ID, "I'll be posting new freebies from now on as soon as I find some. 😉")
-				if err != nil {
-					log.Println(err)
-				}
-			}
-		case "":
-		default:
-			err := b.SendMsgWithMarkdown(update.Message.Chat.ID, "Unknown command 🧐\\. Type _*/*_")
-			if err != nil {
-				log.Println(err)
-			}
-		}
+	b.router.route(b, update.Message)
+}
+
+func (b *Bot) handleCallback(query *tgbotapi.CallbackQuery) {
+	if query.Message == nil {
+		return
+	}
+	b.detectLanguage(query.Message.Chat.ID, query.From)
+
+	if isSettingsCallback(query.Data) && !b.isAdmin(query.Message.Chat, query.From) {
+		b.answerAdminsOnly(query)
+		return
+	}
+
+	switch {
+	case strings.HasPrefix(query.Data, searchCallbackPrefix):
+		b.handleSearchPage(query)
+	case strings.HasPrefix(query.Data, platformCallbackPrefix):
+		b.togglePlatform(query)
+	case strings.HasPrefix(query.Data, languageCallbackPrefix):
+		b.setLanguage(query)
+	case strings.HasPrefix(query.Data, feedbackCallbackPrefix):
+		b.handleFeedback(query)
+	case strings.HasPrefix(query.Data, pickCallbackPrefix):
+		b.handlePickPost(query)
+	case strings.HasPrefix(query.Data, hideCallbackPrefix):
+		b.handleHidePlatform(query)
+	case strings.HasPrefix(query.Data, broadcastCallbackPrefix):
+		b.handleBroadcast(query)
+	default:
+		log.Printf("Unknown callback data '%s' from chat id '%d'", query.Data, query.Message.Chat.ID)
 	}
 }
 
@@ -163,6 +279,11 @@ func (b *Bot) WatchNewPosts(ctx context.Context) {
 		currentSubscribers.Set(float64(len(subscribers)))
 
 		now := time.Now().UTC()
+		channelsLastPost, err := b.channelsLastPost(now)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
 
 		earlierstLastPost := now
 		for _, s := range subscribers {
@@ -170,36 +291,51 @@ func (b *Bot) WatchNewPosts(ctx context.Context) {
 				earlierstLastPost = s.LastPost
 			}
 		}
+		for _, lastPost := range channelsLastPost {
+			if earlierstLastPost.After(lastPost) {
+				earlierstLastPost = lastPost
+			}
+		}
 
-		fetch, err := b.fetchLinks(earlierstLastPost)
+		fetchedLinks, err := b.fetchLinks(ctx, earlierstLastPost)
 		if err != nil {
 			log.Println(err)
 			continue
 		}
 
-		if len(fetch.Links) == 0 {
-			time.Sleep(time.Duration(rnd.Intn(60*4)+60) * time.Second)
+		if len(fetchedLinks) == 0 {
+			b.waitForFetch(time.Duration(rnd.Intn(60*4)+60) * time.Second)
 			continue
 		}
 
-		links := filterLinks(fetch.Links)
+		allowedLinks := b.filterLinks(fetchedLinks)
 
 		var wg sync.WaitGroup
 		for _, s := range subscribers {
 			wg.Go(func() {
-
-				links = getLinksAfter(links, s.LastPost)
+				// Digest subscribers get their links from SendDueDigests
+				preferences, err := b.storage.GetPreferences(s.ChatID)
 				if err != nil {
 					log.Println(err)
+				} else if preferences.DeliveryMode != db.DeliveryInstant {
+					return
+				} else if isQuiet(preferences, now) {
+					// Last post stays behind, so the links are sent once quiet hours end
 					return
 				}
-				links = b.filterDeliveredLinks(s.ChatID, fetch.Id, links)
+
+				links := getLinksAfter(allowedLinks, s.LastPost)
+				links = b.filterByPreferences(s.ChatID, links)
+				links = b.filterDeliveredLinks(s.ChatID, links)
 
 				if len(links) == 0 {
 					return
 				}
-				b.SendMsg(s.ChatID, "Just found some new freebies for you 😉")
-				b.sendLinks(s.ChatID, links)
+				err = b.enqueue(s.ChatID, b.linkMessages(s.ChatID, locale.NewLinksHeader, links))
+				if err != nil {
+					log.Println(err)
+					return
+				}
 				err = b.storage.UpdateLastPost(s.ChatID, now)
 				if err != nil {
 					log.Println(err)
@@ -208,14 +344,31 @@ func (b *Bot) WatchNewPosts(ctx context.Context) {
 
 			})
 		}
-		time.Sleep(time.Duration(rnd.Intn(60*4)+60) * time.Second)
+		for _, channel := range b.channels {
+			if b.disabledChannels.contains(channel.ChatID) {
+				continue
+			}
+			wg.Go(func() {
+				links := getLinksAfter(allowedLinks, channelsLastPost[channel.ChatID])
+				links = b.filterDeliveredLinks(channel.ChatID, links)
+				if err := b.enqueue(channel.ChatID, b.channelMessages(channel, links)); err != nil {
+					log.Println(err)
+					return
+				}
+				// Channels get every allowed link, so nothing older is left to send
+				if err := b.storage.UpdateChannelLastPost(channel.ChatID, now); err != nil {
+					log.Println(err)
+				}
+			})
+		}
+		b.waitForFetch(time.Duration(rnd.Intn(60*4)+60) * time.Second)
 		wg.Wait()
 	}
 }
 
 func (b *Bot) SendMsg(chatId int64, message string) error {
 	msg := tgbotapi.NewMessage(chatId, message)
-	_, err := b.botApi.Send(msg)
+	_, err := b.send(chatId, msg)
 	return err
 }
 
@@ -223,55 +376,284 @@ func (b *Bot) SendMsgWithMarkdown(chatId int64, message string) error {
 	msg := tgbotapi.NewMessage(chatId, message)
 	msg.ParseMode = "MarkdownV2"
 	msg.DisableWebPagePreview = true
-	_, err := b.botApi.Send(msg)
+	_, err := b.send(chatId, msg)
 	return err
 }
 
-func (b *Bot) SendPostsToUser(chatID int64, sinceDays int) {
-	sinceTime := time.Now().UTC().AddDate(0, 0, -sinceDays)
-	fetch, err := b.fetchLinks(sinceTime)
-	if err != nil {
-		log.Println(err)
+// SendHTML sends an HTML message with an optional inline keyboard
+func (b *Bot) SendHTML(chatId int64, message string, keyboard *tgbotapi.InlineKeyboardMarkup) error {
+	msg := tgbotapi.NewMessage(chatId, message)
+	msg.ParseMode = tgbotapi.ModeHTML
+	if keyboard != nil {
+		msg.ReplyMarkup = keyboard
+	} else {
+		msg.DisableWebPagePreview = true
 	}
-	if len(fetch.Links) == 0 {
+	_, err := b.send(chatId, msg)
+	return err
+}
+
+// SendPostsToUser sends the links posted since the start of the chat's local
+// day sinceDays days ago
+func (b *Bot) SendPostsToUser(chatID int64, sinceDays int) {
+	sinceTime := startOfDay(b.localNow(chatID)).AddDate(0, 0, -sinceDays)
+	links := b.linksForUser(chatID, sinceTime)
+	if len(links) == 0 {
 		if sinceDays == 0 {
-			b.SendMsg(chatID, "No freebies for today 😕")
+			b.SendText(chatID, locale.NoLinksToday)
 		} else {
-			b.SendMsg(chatID, "No freebies so far 😕")
+			b.SendText(chatID, locale.NoLinks)
 		}
 	} else {
-		b.SendMsg(chatID, "Here are some freebies for you 😉")
-		b.sendLinks(chatID, fetch.Links)
+		b.sendMessages(chatID, b.linkMessages(chatID, locale.LinksHeader, links))
+	}
+}
+
+// linksForUser returns the stored links posted after sinceTime allowed by
+// the rules and by the preferences of the chat
+func (b *Bot) linksForUser(chatId int64, sinceTime time.Time) []fetchers.Link {
+	links, err := b.storedLinksSince(sinceTime)
+	if err != nil {
+		log.Println(err)
 	}
+	links = b.filterLinks(links)
+	return b.filterByPreferences(chatId, links)
 }
 
-func (b *Bot) fetchLinks(sinceTime time.Time) (fetchers.Fetch, error) {
-	fetch, err := b.links.Fetch(sinceTime)
+// storedLinksSince reads the links posted after sinceTime from the database,
+// merged like the fetched ones and without the muted sources. The sources are
+// fetched first only if the stored posts are stale.
+func (b *Bot) storedLinksSince(sinceTime time.Time) ([]fetchers.Link, error) {
+	b.refreshStalePosts(sinceTime, time.Now())
+
+	posts, err := b.storage.ReadPostsSince(sinceTime)
 	if err != nil {
-		return fetchers.Fetch{}, err
+		return nil, err
+	}
+	links := make([]fetchers.Link, 0, len(posts))
+	for _, post := range posts {
+		links = append(links, postToLink(post))
+	}
+	return b.dropMutedLinks(mergeLinks(links)), nil
+}
+
+// refreshStalePosts fetches the sources when WatchNewPosts hasn't done it
+// lately, e.g. right after the start. Failed fetches aren't retried before
+// the posts get stale again, the stored posts are used meanwhile.
+func (b *Bot) refreshStalePosts(sinceTime, now time.Time) {
+	b.refreshMu.Lock()
+	defer b.refreshMu.Unlock()
+
+	if !b.isStale(now) || now.Sub(b.lastRefreshAt) < StalePostsAge {
+		return
+	}
+	b.lastRefreshAt = now
+
+	log.Printf("Stored posts are stale, fetching posts since %s", sinceTime)
+	if _, err := b.fetchLinks(context.Background(), sinceTime); err != nil {
+		log.Println(err)
+	}
+}
+
+func (b *Bot) isStale(now time.Time) bool {
+	lastFetchAt := b.lastFetchAt.Load()
+	return lastFetchAt == 0 || now.Sub(time.Unix(0, lastFetchAt)) > StalePostsAge
+}
+
+// fetchLinks runs all registered sources concurrently, each one bounded by its own
+// timeout, and merges their links. It fails only if every source has failed.
+func (b *Bot) fetchLinks(ctx context.Context, sinceTime time.Time) ([]fetchers.Link, error) {
+	sources := b.activeSources()
+	results := make([][]fetchers.Link, len(sources))
+	errs := make([]error, len(sources))
+
+	var wg sync.WaitGroup
+	for i, source := range sources {
+		wg.Go(func() {
+			sourceCtx, cancel := context.WithTimeout(ctx, source.Timeout)
+			defer cancel()
+
+			fetch, err := source.Fetcher.Fetch(sourceCtx, sinceTime)
+			if err != nil {
+				sourceErrors.WithLabelValues(source.Name).Inc()
+				errs[i] = fmt.Errorf("Failed to fetch source '%s': %w", source.Name, err)
+				return
+			}
+			for j := range fetch.Links {
+				fetch.Links[j].Source = source.Name
+			}
+			if err := b.storage.UpdatePostsSource(fetch.Id, source.Name); err != nil {
+				log.Println(err)
+			}
+			b.countRuleMatches(fetch.Id)
+			results[i] = fetch.Links
+		})
 	}
+	wg.Wait()
+
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			log.Println(err)
+			failed += 1
+		}
+	}
+	if len(sources) > 0 && failed == len(sources) {
+		return nil, errors.Join(errs...)
+	}
+	b.lastFetchAt.Store(time.Now().UnixNano())
+
+	links := mergeLinks(results...)
 	linksRequests.Add(1)
-	if len(fetch.Links) != 0 {
-		log.Printf("Fetched %d posts in total for %s", len(fetch.Links), sinceTime.String())
-		fetchedRequests.Add(float64(len(fetch.Links)))
+	if len(links) != 0 {
+		log.Printf("Fetched %d posts in total for %s", len(links), sinceTime.String())
+		fetchedRequests.Add(float64(len(links)))
+	}
+	return links, nil
+}
+
+// countRuleMatches counts the rules matching the posts first stored by the
+// fetch, the links found before are fetched again and again
+func (b *Bot) countRuleMatches(fetchId int64) {
+	posts, err := b.storage.ReadFetchPosts(fetchId)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	links := make([]fetchers.Link, 0, len(posts))
+	for _, post := range posts {
+		links = append(links, postToLink(post))
+	}
+	b.rules.CountMatches(links)
+}
+
+// mergeLinks joins links of several sources, newest first. A link found by
+// several sources is kept once, the first source wins.
+func mergeLinks(sourceLinks ...[]fetchers.Link) []fetchers.Link {
+	seen := map[string]bool{}
+	links := []fetchers.Link{}
+	for _, sl := range sourceLinks {
+		for _, link := range sl {
+			key := linkKey(link.Link)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			links = append(links, link)
+		}
+	}
+
+	sort.SliceStable(links, func(i, j int) bool {
+		return links[i].Date.After(links[j].Date)
+	})
+	return links
+}
+
+// mergePosts keeps the first of the posts with the same link, the order
+// of the posts is kept
+func mergePosts(posts []db.Post) []db.Post {
+	seen := map[string]bool{}
+	return slices.DeleteFunc(posts, func(post db.Post) bool {
+		key := linkKey(post.Link)
+		if seen[key] {
+			return true
+		}
+		seen[key] = true
+		return false
+	})
+}
+
+func linkKey(link string) string {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || u.Host == "" {
+		return strings.TrimSuffix(strings.TrimSpace(link), "/")
+	}
+	key := strings.TrimPrefix(strings.ToLower(u.Host), "www.") + strings.TrimSuffix(u.Path, "/")
+	if u.RawQuery != "" {
+		key += "?" + u.RawQuery
+	}
+	return key
+}
+
+// linkMessages makes a message per link after the header, or a single digest
+// if the chat asked for it or there are too many links.
+func (b *Bot) linkMessages(chatId int64, header locale.Key, links []fetchers.Link) []db.OutboxMessage {
+	preferences, err := b.storage.GetPreferences(chatId)
+	if err != nil {
+		log.Println(err)
+	}
+	lang := preferredLanguage(preferences)
+	now := time.Now().In(userLocation(preferences))
+
+	if preferences.BatchLinks || len(links) > MaxSeparateLinks {
+		return b.digestMessages(chatId, locale.Get(lang, header), links, lang, now)
+	}
+
+	messages := []db.OutboxMessage{{ChatID: chatId, Text: locale.Get(lang, header)}}
+	for _, link := range links {
+		keyboard := linkKeyboard(link, lang)
+		post, err := b.storage.GetPostByLink(link.Link)
+		if err == nil {
+			feedback := feedbackKeyboard(link, post.Id, "", false, lang)
+			keyboard = &feedback
+		} else {
+			log.Println(err)
+		}
+		message := htmlMessage(chatId, formatLink(link, lang, now), keyboard)
+		if err == nil {
+			message.PostIDs = []int64{post.Id}
+		}
+		messages = append(messages, message)
 	}
-	return fetch, nil
+	return messages
 }
 
-func (b *Bot) sendLinks(chatId int64, links []fetchers.Link) {
+// digestMessages splits the digest into messages, the posts are delivered
+// with the last one. Every message has the buttons picking its links.
+func (b *Bot) digestMessages(chatId int64, header string, links []fetchers.Link, lang string, now time.Time) []db.OutboxMessage {
+	postIds := make([]int64, len(links))
+	for i, link := range links {
+		post, err := b.storage.GetPostByLink(link.Link)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		postIds[i] = post.Id
+	}
+
+	messages := []db.OutboxMessage{}
+	first := 0
+	for _, part := range formatDigest(header, links, lang, now) {
+		message := htmlMessage(chatId, part.text, digestKeyboard(postIds[first:first+part.count], first))
+		message.DisableWebPagePreview = true
+		messages = append(messages, message)
+		first += part.count
+	}
+	if len(messages) != 0 {
+		messages[len(messages)-1].PostIDs = slices.DeleteFunc(postIds, func(id int64) bool { return id == 0 })
+	}
+	return messages
+}
+
+func (b *Bot) postIds(links []fetchers.Link) []int64 {
+	postIds := []int64{}
 	for _, link := range links {
-		b.SendMsg(chatId, link.Link)
+		post, err := b.storage.GetPostByLink(link.Link)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		postIds = append(postIds, post.Id)
 	}
-	log.Printf("%d posts send to subscriber: %d", len(links), chatId)
-	freebieDeliveries.Add(float64(len(links)))
+	return postIds
 }
 
-func (b *Bot) filterDeliveredLinks(chatId, fetchId int64, links []fetchers.Link) []fetchers.Link {
+func (b *Bot) filterDeliveredLinks(chatId int64, links []fetchers.Link) []fetchers.Link {
 	filteredLinks := []fetchers.Link{}
 	for _, link := range links {
-		delivered, err := b.checkIfPostDelivered(chatId, link.Link, fetchId)
+		delivered, err := b.checkIfPostDelivered(chatId, link)
 		if err != nil {
-			log.Printf("Failed to check post for delivery for chatId '%d', link '%s': %s", fetchId, link.Link, err.Error())
+			log.Printf("Failed to check post for delivery for chatId '%d', link '%s': %s", chatId, link.Link, err.Error())
 		}
 		if delivered {
 			continue
@@ -282,26 +664,26 @@ func (b *Bot) filterDeliveredLinks(chatId, fetchId int64, links []fetchers.Link)
 	return filteredLinks
 }
 
-func (b *Bot) checkIfPostDelivered(chatId int64, link string, fetchId int64) (bool, error) {
-	post, err := b.storage.GetPostByLink(link)
+func (b *Bot) checkIfPostDelivered(chatId int64, link fetchers.Link) (bool, error) {
+	post, err := b.storage.GetPostByLink(link.Link)
 	if err != nil {
-		return false, fmt.Errorf("Failed to get post post for fetch id '%d', link '%s': %w", fetchId, link, err)
+		return false, fmt.Errorf("Failed to get post for source '%s', link '%s': %w", link.Source, link.Link, err)
 	}
 
 	deliveredPost, err := b.storage.GetDeliveredPost(post.Id, chatId)
 	if err == nil {
-		log.Printf("Skipping post, already delivered for post id '%d', chat id '%d', link '%s' on delivery date %s", post.Id, chatId, link, deliveredPost.DeliveryDate.String())
+		log.Printf("Skipping post, already delivered for post id '%d', chat id '%d', link '%s' on delivery date %s", post.Id, chatId, link.Link, deliveredPost.DeliveryDate.String())
 		return true, nil
 	} else if !errors.Is(err, sql.ErrNoRows) {
-		return false, fmt.Errorf("Failed to get post delivered post for fetch id '%d', chatId '%d': %w", fetchId, chatId, err)
+		return false, fmt.Errorf("Failed to get delivered post for post id '%d', chatId '%d': %w", post.Id, chatId, err)
 	}
 
-	err = b.storage.StoreDeliveredPost(post.Id, chatId)
+	// Posts waiting in the outbox are delivered once sent
+	queued, err := b.storage.IsPostQueued(post.Id, chatId)
 	if err != nil {
-		return false, fmt.Errorf("Failed to store delivered post for fetch id '%d', chatId '%d': %w", fetchId, chatId, err)
+		return false, fmt.Errorf("Failed to check queued post for post id '%d', chatId '%d': %w", post.Id, chatId, err)
 	}
-
-	return false, nil
+	return queued, nil
 }
 
 func getLinksAfter(links []fetchers.Link, date time.Time) []fetchers.Link {
@@ -315,34 +697,12 @@ func getLinksAfter(links []fetchers.Link, date time.Time) []fetchers.Link {
 	return result
 }
 
-func filterLinks(links []fetchers.Link) []fetchers.Link {
+func (b *Bot) filterLinks(links []fetchers.Link) []fetchers.Link {
 	filteredLinks := []fetchers.Link{}
 	for _, link := range links {
-		if isLinkAllowed(link.Link) {
+		if b.rules.IsAllowed(link) {
 			filteredLinks = append(filteredLinks, link)
 		}
 	}
 	return filteredLinks
 }
-
-var rules = map[string]func(link string) bool{
-	"skip_amazon": func(link string) bool {
-		return !strings.Contains(link, "amazon.com")
-	},
-	"skip_reddit": func(link string) bool {
-		return !strings.HasPrefix(link, "/r/")
-	},
-	"skip_x_com": func(link string) bool {
-		return !strings.HasPrefix(link, "https://x.com")
-	},
-}
-
-func isLinkAllowed(link string) bool {
-	for name, isAllowed := range rules {
-		if !isAllowed(link) {
-			fmt.Printf("Link %s is filtered by rule %s\n", link, name)
-			return false
-		}
-	}
-	return true
-}
@@ -0,0 +1,113 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/freebies-telegram-bot/internal/db"
+	"github.com/freebies-telegram-bot/internal/fetchers"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_isStale(t *testing.T) {
+	now := time.Now()
+	b := &Bot{}
+	assert.True(t, b.isStale(now))
+
+	b.lastFetchAt.Store(now.Add(-StalePostsAge / 2).UnixNano())
+	assert.False(t, b.isStale(now))
+	assert.True(t, b.isStale(now.Add(StalePostsAge)))
+}
+
+func (s *fakeStorage) ReadPostsSince(sinceTime time.Time) ([]db.Post, error) {
+	posts := []db.Post{}
+	for _, post := range s.posts {
+		if post.PostedAt.After(sinceTime) {
+			posts = append(posts, post)
+		}
+	}
+	return posts, nil
+}
+
+func (s *fakeStorage) ReadMutedSources() ([]string, error) {
+	return s.mutedSources, nil
+}
+
+func (s *fakeStorage) UpdatePostsSource(fetchId int64, source string) error {
+	return nil
+}
+
+func (s *fakeStorage) ReadFetchPosts(fetchId int64) ([]db.Post, error) {
+	return nil, nil
+}
+
+// fakeSources has a single source counting its fetches
+type fakeSources struct {
+	fetches int
+	err     error
+}
+
+func (s *fakeSources) Sources() []fetchers.Source {
+	return []fetchers.Source{{Name: "fake", Fetcher: s, Timeout: time.Second}}
+}
+
+func (s *fakeSources) Fetch(ctx context.Context, sinceTime time.Time) (fetchers.Fetch, error) {
+	s.fetches += 1
+	return fetchers.Fetch{}, s.err
+}
+
+// denyTitles rejects the links with the titles
+type denyTitles []string
+
+func (r denyTitles) IsAllowed(link fetchers.Link) bool {
+	return !slices.Contains(r, link.Title)
+}
+
+func (r denyTitles) CountMatches(links []fetchers.Link) {}
+
+func Test_linksForUser(t *testing.T) {
+	now := time.Now().UTC()
+	sinceTime := now.Add(-24 * time.Hour)
+	witcher := db.Post{Link: "https://store.steampowered.com/app/1/", Title: "Witcher", Platform: fetchers.PlatformSteam, PostedAt: now.Add(-time.Hour), Source: "freegamefindings"}
+	giveaway := db.Post{Link: "https://example.com/giveaway", Title: "Giveaway", PostedAt: now.Add(-6 * time.Hour)}
+	posts := []db.Post{
+		witcher,
+		// Found again by another source under another form of the link
+		{Link: "https://www.store.steampowered.com/app/1", Title: "Witcher", Platform: fetchers.PlatformSteam, PostedAt: now.Add(-2 * time.Hour), Source: "pikabu"},
+		{Link: "https://store.epicgames.com/p/hades", Title: "Hades", Platform: fetchers.PlatformEpic, PostedAt: now.Add(-3 * time.Hour), Source: "epicgames"},
+		{Link: "https://store.steampowered.com/app/2/", Title: "Soundtrack", Platform: fetchers.PlatformSteam, PostedAt: now.Add(-4 * time.Hour), Source: "freegamefindings"},
+		{Link: "https://store.steampowered.com/app/3/", Title: "Muted", Platform: fetchers.PlatformSteam, PostedAt: now.Add(-5 * time.Hour), Source: "muted"},
+		giveaway,
+		{Link: "https://store.steampowered.com/app/4/", Title: "Old", Platform: fetchers.PlatformSteam, PostedAt: now.Add(-48 * time.Hour), Source: "freegamefindings"},
+	}
+
+	t.Run("rules and preferences", func(t *testing.T) {
+		storage := newFakeStorage()
+		storage.posts = posts
+		storage.mutedSources = []string{"muted"}
+		storage.preferences[1] = db.Preferences{HiddenPlatforms: []string{fetchers.PlatformEpic}}
+		sources := &fakeSources{}
+		b := &Bot{storage: storage, sources: sources, rules: denyTitles{"Soundtrack"}}
+		b.lastFetchAt.Store(now.UnixNano())
+
+		assert.Equal(t, []fetchers.Link{postToLink(witcher), postToLink(giveaway)}, b.linksForUser(1, sinceTime))
+		// The stored posts are fresh
+		assert.Equal(t, 0, sources.fetches)
+	})
+
+	t.Run("stale posts are fetched once", func(t *testing.T) {
+		storage := newFakeStorage()
+		storage.posts = posts
+		sources := &fakeSources{err: errors.New("timeout")}
+		b := &Bot{storage: storage, sources: sources, rules: denyTitles{}}
+
+		b.linksForUser(1, sinceTime)
+		assert.Equal(t, 1, sources.fetches)
+		// The fetch has failed, the stored posts are used until they get stale again
+		assert.Len(t, b.linksForUser(1, sinceTime), 5)
+		assert.Equal(t, 1, sources.fetches)
+	})
+}
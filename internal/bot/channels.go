@@ -0,0 +1,213 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/freebies-telegram-bot/internal/db"
+	"github.com/freebies-telegram-bot/internal/fetchers"
+	"github.com/freebies-telegram-bot/internal/locale"
+)
+
+// New channels get the links posted within this period
+const ChannelsLookback = 24 * time.Hour
+
+// Channel is run by the operator and gets every freebie allowed by the
+// global rules, regardless of any subscriber preferences.
+type Channel struct {
+	ChatID int64 `json:"chat_id"`
+	// html/template executed for every link, the message sent to
+	// subscribers is used if empty
+	Template string `json:"template"`
+	Language string `json:"language"`
+
+	template *template.Template
+}
+
+// disabledChannels keeps the channels the bot can't post to. They come from
+// the config, so they stay disabled until the restart instead of being
+// unsubscribed.
+type disabledChannels struct {
+	mu    sync.Mutex
+	chats map[int64]bool
+}
+
+func newDisabledChannels() *disabledChannels {
+	return &disabledChannels{chats: map[int64]bool{}}
+}
+
+// add reports whether the channel wasn't disabled before
+func (c *disabledChannels) add(chatId int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.chats[chatId] {
+		return false
+	}
+	c.chats[chatId] = true
+	return true
+}
+
+func (c *disabledChannels) contains(chatId int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.chats[chatId]
+}
+
+// channelPost is passed to the template of a channel
+type channelPost struct {
+	Link         string
+	Title        string
+	Source       string
+	Permalink    string
+	PlatformName string
+	DealTypeName string
+	Dates        string
+}
+
+// LoadChannels reads the channels from a JSON file, there are none if path is empty
+func LoadChannels(path string) ([]Channel, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read channels file '%s': %w", path, err)
+	}
+	channels, err := ParseChannels(data)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to load channels file '%s': %w", path, err)
+	}
+	return channels, nil
+}
+
+func ParseChannels(data []byte) ([]Channel, error) {
+	var channels []Channel
+	if err := json.Unmarshal(data, &channels); err != nil {
+		return nil, err
+	}
+
+	for i, channel := range channels {
+		if channel.ChatID == 0 {
+			return nil, fmt.Errorf("Channel %d has no chat_id", i)
+		}
+		if channel.Template == "" {
+			continue
+		}
+		tmpl, err := template.New(fmt.Sprint(channel.ChatID)).Parse(channel.Template)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid template of channel '%d': %w", channel.ChatID, err)
+		}
+		channels[i].template = tmpl
+	}
+	return channels, nil
+}
+
+func (c Channel) language() string {
+	if c.Language == "" {
+		return locale.Default
+	}
+	return c.Language
+}
+
+func (c Channel) format(link fetchers.Link, now time.Time) (string, error) {
+	lang := c.language()
+	if c.template == nil {
+		return formatLink(link, lang, now), nil
+	}
+
+	post := channelPost{
+		Link:      link.Link,
+		Title:     linkTitle(link),
+		Source:    link.Source,
+		Permalink: link.Permalink,
+		Dates:     linkDates(link, lang, now),
+	}
+	if link.Platform != fetchers.PlatformOther {
+		post.PlatformName = fetchers.PlatformNames[link.Platform]
+	}
+	if link.DealType != fetchers.DealOther {
+		post.DealTypeName = fetchers.DealTypeNames[link.DealType]
+	}
+
+	var message strings.Builder
+	if err := c.template.Execute(&message, post); err != nil {
+		return "", fmt.Errorf("Unable to format link %s for channel '%d': %w", link.Link, c.ChatID, err)
+	}
+	return message.String(), nil
+}
+
+// channelsLastPost returns the time of the last post delivered to each
+// configured channel
+func (b *Bot) channelsLastPost(now time.Time) (map[int64]time.Time, error) {
+	if len(b.channels) == 0 {
+		return nil, nil
+	}
+	stored, err := b.storage.ReadChannelsLastPost()
+	if err != nil {
+		return nil, err
+	}
+
+	lastPosts := map[int64]time.Time{}
+	for _, channel := range b.channels {
+		lastPost, ok := stored[channel.ChatID]
+		if !ok {
+			lastPost = now.Add(-ChannelsLookback)
+		}
+		lastPosts[channel.ChatID] = lastPost
+	}
+	return lastPosts, nil
+}
+
+// channelMessages makes a message per link formatted with the template of
+// the channel
+func (b *Bot) channelMessages(channel Channel, links []fetchers.Link) []db.OutboxMessage {
+	now := time.Now().UTC()
+	messages := []db.OutboxMessage{}
+	for _, link := range links {
+		text, err := channel.format(link, now)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		message := htmlMessage(channel.ChatID, text, linkKeyboard(link, channel.language()))
+		message.PostIDs = b.postIds([]fetchers.Link{link})
+		messages = append(messages, message)
+	}
+	return messages
+}
+
+func (b *Bot) isChannel(chatId int64) bool {
+	return slices.ContainsFunc(b.channels, func(channel Channel) bool {
+		return channel.ChatID == chatId
+	})
+}
+
+// disableChannel stops sending to a channel that can't get messages and
+// tells the operators about it once
+func (b *Bot) disableChannel(chatId int64, reason string) {
+	if !b.disabledChannels.add(chatId) {
+		return
+	}
+	log.Printf("Disabling channel id '%d': %s", chatId, reason)
+
+	messages := make([]db.OutboxMessage, 0, len(b.operators))
+	for _, operator := range b.operators {
+		text := locale.Get(b.language(operator), locale.ChannelUnavailable, chatId, reason)
+		messages = append(messages, db.OutboxMessage{ChatID: operator, Text: text})
+	}
+	if len(messages) == 0 {
+		return
+	}
+	if err := b.storage.EnqueueMessages(messages, time.Now()); err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,67 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/freebies-telegram-bot/internal/fetchers"
+	"github.com/freebies-telegram-bot/internal/locale"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseChannels(t *testing.T) {
+	channels, err := ParseChannels([]byte(`[
+		{"chat_id": -1001, "language": "ru"},
+		{"chat_id": -1002, "template": "<b>{{.Title}}</b> {{.PlatformName}} {{.Dates}}\n{{.Link}}"}
+	]`))
+	require.NoError(t, err)
+	require.Len(t, channels, 2)
+
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	link := fetchers.Link{
+		Link:      "https://store.steampowered.com/app/1/",
+		Title:     "Tom & Jerry",
+		Platform:  fetchers.PlatformSteam,
+		DealType:  fetchers.DealGame,
+		ExpiresAt: time.Date(2026, 10, 22, 15, 0, 0, 0, time.UTC),
+	}
+
+	message, err := channels[0].format(link, now)
+	require.NoError(t, err)
+	assert.Equal(t, formatLink(link, locale.Russian, now), message)
+
+	message, err = channels[1].format(link, now)
+	require.NoError(t, err)
+	assert.Equal(t, "<b>Tom &amp; Jerry</b> Steam until 22 Oct 15:00 UTC\nhttps://store.steampowered.com/app/1/", message)
+
+	_, err = ParseChannels([]byte(`[{"chat_id": -1001, "template": "{{.Title"}]`))
+	assert.Error(t, err)
+	_, err = ParseChannels([]byte(`[{"template": "{{.Title}}"}]`))
+	assert.Error(t, err)
+}
+
+func (s *fakeStorage) ReadChannelsLastPost() (map[int64]time.Time, error) {
+	return s.channelsLastPost, nil
+}
+
+func Test_channelsLastPost(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	storage := newFakeStorage()
+	storage.channelsLastPost[-1001] = now.Add(-5 * time.Minute)
+	// Not configured anymore
+	storage.channelsLastPost[-1003] = now.Add(-48 * time.Hour)
+
+	b := &Bot{storage: storage}
+	lastPosts, err := b.channelsLastPost(now)
+	require.NoError(t, err)
+	assert.Empty(t, lastPosts)
+
+	b.channels = []Channel{{ChatID: -1001}, {ChatID: -1002}}
+	lastPosts, err = b.channelsLastPost(now)
+	require.NoError(t, err)
+	assert.Equal(t, map[int64]time.Time{
+		-1001: now.Add(-5 * time.Minute),
+		-1002: now.Add(-ChannelsLookback),
+	}, lastPosts)
+}
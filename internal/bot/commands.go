@@ -0,0 +1,350 @@
+package bot
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/freebies-telegram-bot/internal/db"
+	"github.com/freebies-telegram-bot/internal/locale"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// commandScope tells where a command can be used
+type commandScope uint8
+
+const (
+	scopePrivate commandScope = 1 << iota
+	scopeGroup
+	// Only administrators may use the command in groups, the command changes
+	// the settings of the whole chat
+	scopeAdmin
+	// Only the operator chats of the bot may use the command
+	scopeOperator
+
+	scopeEverywhere = scopePrivate | scopeGroup
+	scopeSettings   = scopeEverywhere | scopeAdmin
+	scopeOperations = scopeEverywhere | scopeOperator
+)
+
+// Used as argsParser.max for the commands taking any number of arguments
+const anyArgs = -1
+
+// commandArgs are the arguments of a command split by whitespace
+type commandArgs []string
+
+func (a commandArgs) String() string {
+	return strings.Join(a, " ")
+}
+
+// argsParser checks the number of arguments of a command, usage is sent
+// back when it doesn't match
+type argsParser struct {
+	min   int
+	max   int
+	usage locale.Key
+}
+
+// parse splits the arguments, commands without a parser ignore them
+func (p *argsParser) parse(text string) (commandArgs, bool) {
+	if p == nil {
+		return nil, true
+	}
+	args := commandArgs(strings.Fields(text))
+	return args, len(args) >= p.min && (p.max == anyArgs || len(args) <= p.max)
+}
+
+type commandHandler func(b *Bot, message *tgbotapi.Message, args commandArgs) error
+
+// command is an entry of the command table. Its description is the
+// "command_<name>" message of the catalogs.
+type command struct {
+	name  string
+	scope commandScope
+	// nil for the commands without arguments
+	args    *argsParser
+	handler commandHandler
+	// Hidden commands are left out of the menu and of /help
+	hidden bool
+}
+
+func (c command) availableIn(chat *tgbotapi.Chat) bool {
+	if chat.IsPrivate() {
+		return c.scope&scopePrivate != 0
+	}
+	return c.scope&scopeGroup != 0
+}
+
+// commandTable lists the commands in the order they are shown in the menu
+// and in /help
+func commandTable() []command {
+	return []command{
+		{name: "start", scope: scopeSettings, handler: (*Bot).handleStart, hidden: true},
+		{name: "today", scope: scopeEverywhere, handler: postsSince(0)},
+		{name: "yesterday", scope: scopeEverywhere, handler: postsSince(1)},
+		{name: "week", scope: scopeEverywhere, handler: postsSince(7)},
+		{name: "month", scope: scopeEverywhere, handler: postsSince(30)},
+		{
+			name:  "search",
+			scope: scopeEverywhere,
+			args:  &argsParser{1, anyArgs, locale.SearchUsage},
+			handler: func(b *Bot, message *tgbotapi.Message, args commandArgs) error {
+				return b.Search(message, args.String())
+			},
+		},
+		{name: "receive", scope: scopeSettings, handler: withChat((*Bot).ToggleSubscription)},
+		{name: "platforms", scope: scopeSettings, handler: withChat((*Bot).SendPlatformsKeyboard)},
+		// HandleFilterCommand sends the usage of the filter mode of the chat
+		{
+			name:    "filter",
+			scope:   scopeSettings,
+			args:    &argsParser{0, anyArgs, locale.FilterUsage},
+			handler: withArgs((*Bot).HandleFilterCommand),
+		},
+		{name: "batch", scope: scopeSettings, handler: withChat((*Bot).ToggleBatchLinks)},
+		{
+			name:    "digest",
+			scope:   scopeSettings,
+			args:    &argsParser{1, 3, locale.DigestUsage},
+			handler: withArgs((*Bot).HandleDigestCommand),
+		},
+		{
+			name:    "timezone",
+			scope:   scopeSettings,
+			args:    &argsParser{0, 1, locale.TimezoneUsage},
+			handler: withArgs((*Bot).HandleTimezoneCommand),
+		},
+		{
+			name:    "quiet",
+			scope:   scopeSettings,
+			args:    &argsParser{1, 3, locale.QuietUsage},
+			handler: withArgs((*Bot).HandleQuietCommand),
+		},
+		{name: "language", scope: scopeSettings, handler: withChat((*Bot).SendLanguageKeyboard)},
+		{name: "claimed", scope: scopeEverywhere, handler: withChat((*Bot).SendClaimed)},
+		{name: "help", scope: scopeEverywhere, handler: (*Bot).handleHelp},
+
+		{name: "stats", scope: scopeOperations, handler: withChat((*Bot).SendStats)},
+		{name: "subscribers", scope: scopeOperations, handler: withChat((*Bot).SendSubscriberStats)},
+		{
+			name:  "broadcast",
+			scope: scopeOperations,
+			args:  &argsParser{1, anyArgs, locale.BroadcastUsage},
+			// The text keeps its line breaks
+			handler: func(b *Bot, message *tgbotapi.Message, args commandArgs) error {
+				return b.PreviewBroadcast(message.Chat.ID, message.CommandArguments())
+			},
+		},
+		{name: "fetchnow", scope: scopeOperations, handler: withChat((*Bot).FetchNow)},
+		{name: "lastfetch", scope: scopeOperations, handler: withChat((*Bot).SendLastFetch)},
+		{
+			name:    "mute",
+			scope:   scopeOperations,
+			args:    &argsParser{0, 1, locale.MuteUsage},
+			handler: withArgs((*Bot).ToggleSource),
+		},
+	}
+}
+
+// withChat adapts the handlers needing only the chat
+func withChat(handle func(b *Bot, chatId int64) error) commandHandler {
+	return func(b *Bot, message *tgbotapi.Message, args commandArgs) error {
+		return handle(b, message.Chat.ID)
+	}
+}
+
+// withArgs adapts the handlers parsing the arguments themselves
+func withArgs(handle func(b *Bot, chatId int64, args string) error) commandHandler {
+	return func(b *Bot, message *tgbotapi.Message, args commandArgs) error {
+		return handle(b, message.Chat.ID, args.String())
+	}
+}
+
+func postsSince(days int) commandHandler {
+	return func(b *Bot, message *tgbotapi.Message, args commandArgs) error {
+		b.SendPostsToUser(message.Chat.ID, days)
+		return nil
+	}
+}
+
+// commandRouter runs the handlers of the command table
+type commandRouter struct {
+	commands []command
+	byName   map[string]command
+}
+
+func newCommandRouter(commands []command) *commandRouter {
+	r := &commandRouter{commands: commands, byName: map[string]command{}}
+	for _, c := range commands {
+		r.byName[c.name] = c
+	}
+	return r
+}
+
+// route runs the command of the message, checking where it is used, who
+// sends it and its arguments
+func (r *commandRouter) route(b *Bot, message *tgbotapi.Message) {
+	name := message.Command()
+	if name == "" {
+		return
+	}
+	chatId := message.Chat.ID
+
+	c, ok := r.byName[name]
+	if !ok || !c.availableIn(message.Chat) {
+		// Groups may have other bots with their own commands
+		if !message.Chat.IsPrivate() && !isMentioned(message) {
+			return
+		}
+		if err := b.SendText(chatId, locale.UnknownCommand); err != nil {
+			log.Println(err)
+		}
+		return
+	}
+
+	if c.scope&scopeOperator != 0 && !b.isOperator(chatId) {
+		if err := b.SendText(chatId, locale.OperatorsOnly); err != nil {
+			log.Println(err)
+		}
+		return
+	}
+	if c.scope&scopeAdmin != 0 && !b.canChangeSettings(message) {
+		if err := b.SendText(chatId, locale.AdminsOnly); err != nil {
+			log.Println(err)
+		}
+		return
+	}
+
+	args, ok := c.args.parse(message.CommandArguments())
+	if !ok {
+		if err := b.SendText(chatId, c.args.usage); err != nil {
+			log.Println(err)
+		}
+		return
+	}
+
+	if err := c.handler(b, message, args); err != nil {
+		log.Printf("Command /%s failed in chat id '%d': %s", name, chatId, err.Error())
+	}
+}
+
+// menu returns the visible commands matching the filter with the
+// descriptions in lang
+func (r *commandRouter) menu(lang string, include func(c command) bool) []tgbotapi.BotCommand {
+	botCommands := []tgbotapi.BotCommand{}
+	for _, c := range r.commands {
+		if c.hidden || !include(c) {
+			continue
+		}
+		botCommands = append(botCommands, tgbotapi.BotCommand{
+			Command:     c.name,
+			Description: locale.CommandDescription(lang, c.name),
+		})
+	}
+	return botCommands
+}
+
+// help lists the commands available in the chat, the operator commands
+// only in the operator chats
+func (r *commandRouter) help(lang string, chat *tgbotapi.Chat, operator bool) string {
+	lines := []string{locale.Get(lang, locale.HelpHeader)}
+	include := func(c command) bool {
+		return c.availableIn(chat) && (operator || c.scope&scopeOperator == 0)
+	}
+	for _, c := range r.menu(lang, include) {
+		lines = append(lines, "/"+c.Command+" - "+c.Description)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func inPrivateMenu(c command) bool {
+	return c.scope&scopePrivate != 0 && c.scope&scopeOperator == 0
+}
+
+func inMembersMenu(c command) bool {
+	return c.scope&scopeGroup != 0 && c.scope&(scopeAdmin|scopeOperator) == 0
+}
+
+func inAdminsMenu(c command) bool {
+	return c.scope&scopeGroup != 0 && c.scope&scopeOperator == 0
+}
+
+// SetCommands pushes the command table to the Telegram menu for every
+// language, English is used for the rest. Group members don't see the
+// commands of administrators, only the operator chats see the operator
+// commands.
+func (b *Bot) SetCommands() error {
+	type menuScope struct {
+		scope   tgbotapi.BotCommandScope
+		include func(c command) bool
+	}
+	scopes := []menuScope{
+		{tgbotapi.NewBotCommandScopeDefault(), inPrivateMenu},
+		{tgbotapi.NewBotCommandScopeAllGroupChats(), inMembersMenu},
+		{tgbotapi.NewBotCommandScopeAllChatAdministrators(), inAdminsMenu},
+	}
+	for _, chatId := range b.operators {
+		chat := &tgbotapi.Chat{ID: chatId, Type: "group"}
+		if chatId > 0 {
+			chat.Type = "private"
+		}
+		scopes = append(scopes, menuScope{tgbotapi.NewBotCommandScopeChat(chatId), func(c command) bool {
+			return c.availableIn(chat)
+		}})
+	}
+
+	for _, language := range append([]string{""}, locale.Languages...) {
+		for _, s := range scopes {
+			config := tgbotapi.NewSetMyCommandsWithScopeAndLanguage(s.scope, language, b.router.menu(language, s.include)...)
+			if _, err := b.botApi.Request(config); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (b *Bot) handleStart(message *tgbotapi.Message, args commandArgs) error {
+	chatId := message.Chat.ID
+	err := b.storage.StoreSubscriber(chatId, startOfDay(b.localNow(chatId)))
+	if err != nil {
+		log.Println(err)
+	} else {
+		b.logEvent(chatId, db.EventSubscribed, "/start")
+	}
+
+	lang := b.language(chatId)
+	if err = b.SendMsg(chatId, locale.Get(lang, locale.Welcome)+"\n\n"+b.router.help(lang, message.Chat, b.isOperator(chatId))); err != nil {
+		log.Println(err)
+	}
+	b.SendPostsToUser(chatId, 0)
+	return nil
+}
+
+func (b *Bot) handleHelp(message *tgbotapi.Message, args commandArgs) error {
+	chatId := message.Chat.ID
+	return b.SendMsg(chatId, b.router.help(b.language(chatId), message.Chat, b.isOperator(chatId)))
+}
+
+// ToggleSubscription subscribes the chat to new freebies or unsubscribes it
+func (b *Bot) ToggleSubscription(chatId int64) error {
+	_, err := b.storage.GetSubscriber(int(chatId))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	if err == nil {
+		if err = b.storage.DeleteSubscriber(int(chatId)); err != nil {
+			return err
+		}
+		b.logEvent(chatId, db.EventUnsubscribed, "/receive")
+		return b.SendText(chatId, locale.Unsubscribed)
+	}
+
+	if err = b.storage.StoreSubscriber(chatId, startOfDay(b.localNow(chatId))); err != nil {
+		return err
+	}
+	b.logEvent(chatId, db.EventSubscribed, "/receive")
+	return b.SendText(chatId, locale.Subscribed)
+}
@@ -0,0 +1,78 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/freebies-telegram-bot/internal/locale"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_argsParser(t *testing.T) {
+	var none *argsParser
+	args, ok := none.parse("anything here")
+	assert.True(t, ok)
+	assert.Empty(t, args)
+
+	digest := &argsParser{1, 3, locale.DigestUsage}
+	args, ok = digest.parse("  weekly   fri 18:00 ")
+	assert.True(t, ok)
+	assert.Equal(t, commandArgs{"weekly", "fri", "18:00"}, args)
+	assert.Equal(t, "weekly fri 18:00", args.String())
+	_, ok = digest.parse("")
+	assert.False(t, ok)
+	_, ok = digest.parse("weekly fri 18:00 extra")
+	assert.False(t, ok)
+
+	filter := &argsParser{1, anyArgs, locale.FilterUsage}
+	args, ok = filter.parse("add free weekend")
+	assert.True(t, ok)
+	assert.Equal(t, "add free weekend", args.String())
+}
+
+func Test_commandTable(t *testing.T) {
+	names := map[string]bool{}
+	for _, c := range commandTable() {
+		assert.False(t, names[c.name], "/%s is registered twice", c.name)
+		names[c.name] = true
+		assert.NotNil(t, c.handler, c.name)
+		assert.NotZero(t, c.scope&scopeEverywhere, c.name)
+		if !c.hidden {
+			for _, lang := range locale.Languages {
+				assert.NotEqual(t, "command_"+c.name, locale.CommandDescription(lang, c.name), "/%s has no description in %s", c.name, lang)
+			}
+		}
+	}
+}
+
+func Test_commandRouterHelp(t *testing.T) {
+	r := newCommandRouter([]command{
+		{name: "start", scope: scopeSettings, hidden: true},
+		{name: "today", scope: scopeEverywhere},
+		{name: "language", scope: scopeSettings},
+		{name: "secret", scope: scopePrivate},
+		{name: "stats", scope: scopeOperations},
+	})
+
+	private := &tgbotapi.Chat{ID: 5, Type: "private"}
+	group := &tgbotapi.Chat{ID: -5, Type: "supergroup"}
+	assert.Equal(t, "Here is what I can do:\n"+
+		"/today - List new freebies for today\n"+
+		"/language - Choose the language of the bot\n"+
+		"/secret - command_secret", r.help(locale.English, private, false))
+	assert.Equal(t, "Вот что я умею:\n"+
+		"/today - Халява за сегодня\n"+
+		"/language - Выбрать язык бота", r.help(locale.Russian, group, false))
+	assert.Equal(t, "Вот что я умею:\n"+
+		"/today - Халява за сегодня\n"+
+		"/language - Выбрать язык бота\n"+
+		"/stats - Показать, как работает бот", r.help(locale.Russian, group, true))
+
+	members := r.menu(locale.English, inMembersMenu)
+	assert.Equal(t, []tgbotapi.BotCommand{{Command: "today", Description: "List new freebies for today"}}, members)
+	admins := r.menu(locale.English, inAdminsMenu)
+	assert.Equal(t, []tgbotapi.BotCommand{
+		{Command: "today", Description: "List new freebies for today"},
+		{Command: "language", Description: "Choose the language of the bot"},
+	}, admins)
+}
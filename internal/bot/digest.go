@@ -0,0 +1,161 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/freebies-telegram-bot/internal/db"
+	"github.com/freebies-telegram-bot/internal/fetchers"
+	"github.com/freebies-telegram-bot/internal/locale"
+)
+
+// SendDueDigests sends the posts found since the last delivery to the
+// subscribers whose digest time has come.
+func (b *Bot) SendDueDigests(now time.Time) {
+	subscribers, err := b.storage.ReadSubscribers()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	for _, s := range subscribers {
+		preferences, err := b.storage.GetPreferences(s.ChatID)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		if preferences.DeliveryMode == db.DeliveryInstant {
+			continue
+		}
+		if lastDigestSlot(preferences, now).Compare(preferences.LastDigestAt) <= 0 {
+			continue
+		}
+		// Held until the quiet hours end
+		if isQuiet(preferences, now) {
+			continue
+		}
+
+		posts, err := b.storage.ReadPostsSince(s.LastPost)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		links := make([]fetchers.Link, 0, len(posts))
+		for _, post := range posts {
+			links = append(links, postToLink(post))
+		}
+		links = b.dropMutedLinks(mergeLinks(links))
+		links = b.filterLinks(links)
+		links = b.filterByPreferences(s.ChatID, links)
+		links = b.filterDeliveredLinks(s.ChatID, links)
+
+		if len(links) != 0 {
+			lang := preferredLanguage(preferences)
+			messages := b.digestMessages(s.ChatID, locale.Get(lang, locale.DigestHeader), links, lang, now.In(userLocation(preferences)))
+			if err = b.enqueue(s.ChatID, messages); err != nil {
+				log.Println(err)
+				continue
+			}
+		}
+		if err = b.storage.UpdateLastPost(s.ChatID, now); err != nil {
+			log.Println(err)
+		}
+		if err = b.storage.UpdateLastDigest(s.ChatID, now); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// lastDigestSlot returns the latest scheduled digest time not after now
+// in the timezone of the chat
+func lastDigestSlot(preferences db.Preferences, now time.Time) time.Time {
+	now = now.In(userLocation(preferences))
+	slot := startOfDay(now).Add(time.Duration(preferences.DigestTime) * time.Minute)
+	if slot.After(now) {
+		slot = slot.AddDate(0, 0, -1)
+	}
+	if preferences.DeliveryMode == db.DeliveryWeekly {
+		days := (int(slot.Weekday()) - int(preferences.DigestWeekday) + 7) % 7
+		slot = slot.AddDate(0, 0, -days)
+	}
+	return slot
+}
+
+func postToLink(post db.Post) fetchers.Link {
+	return fetchers.Link{
+		Link:      post.Link,
+		Title:     post.Title,
+		Date:      post.PostedAt,
+		ExpiresAt: post.ExpiresAt,
+		Platform:  post.Platform,
+		DealType:  post.DealType,
+		Flair:     post.Flair,
+		Domain:    post.Domain,
+		Author:    post.Author,
+		Score:     post.Score,
+		Comments:  post.Comments,
+		Permalink: post.Permalink,
+		Source:    post.Source,
+	}
+}
+
+// HandleDigestCommand sets the delivery mode of the chat, args are the
+// arguments of the /digest command.
+func (b *Bot) HandleDigestCommand(chatId int64, args string) error {
+	fields := strings.Fields(strings.ToLower(args))
+	if len(fields) == 0 {
+		return b.SendText(chatId, locale.DigestUsage)
+	}
+
+	preferences, err := b.storage.GetPreferences(chatId)
+	if err != nil {
+		return err
+	}
+	digestTime, weekday := preferences.DigestTime, preferences.DigestWeekday
+
+	mode := fields[0]
+	switch mode {
+	case "off", db.DeliveryInstant:
+		mode = db.DeliveryInstant
+	case db.DeliveryDaily, db.DeliveryWeekly:
+		for _, field := range fields[1:] {
+			if minutes, err := parseClock(field); err == nil {
+				digestTime = minutes
+			} else if day, ok := parseWeekday(field); ok && mode == db.DeliveryWeekly {
+				weekday = day
+			} else {
+				return b.SendText(chatId, locale.DigestUsage)
+			}
+		}
+	default:
+		return b.SendText(chatId, locale.DigestUsage)
+	}
+
+	err = b.storage.UpdateDigest(chatId, mode, digestTime, weekday, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	lang := preferredLanguage(preferences)
+	at := fmt.Sprintf("%s %s", formatClock(digestTime), preferences.Timezone)
+	switch mode {
+	case db.DeliveryDaily:
+		return b.SendMsg(chatId, locale.Get(lang, locale.DigestDaily, at))
+	case db.DeliveryWeekly:
+		return b.SendMsg(chatId, locale.Get(lang, locale.DigestWeekly, locale.Weekday(lang, weekday), at))
+	}
+	return b.SendMsg(chatId, locale.Get(lang, locale.DigestInstant))
+}
+
+// parseWeekday accepts full and three letter day names
+func parseWeekday(s string) (time.Weekday, bool) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := strings.ToLower(day.String())
+		if s == name || s == name[:3] {
+			return day, true
+		}
+	}
+	return 0, false
+}
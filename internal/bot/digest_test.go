@@ -0,0 +1,129 @@
+package bot
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/freebies-telegram-bot/internal/db"
+	"github.com/freebies-telegram-bot/internal/fetchers"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_lastDigestSlot(t *testing.T) {
+	// Sunday
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	daily := db.Preferences{DeliveryMode: db.DeliveryDaily, DigestTime: 9 * 60}
+	assert.Equal(t, time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC), lastDigestSlot(daily, now))
+
+	daily.DigestTime = 18 * 60
+	assert.Equal(t, time.Date(2026, 10, 17, 18, 0, 0, 0, time.UTC), lastDigestSlot(daily, now))
+
+	weekly := db.Preferences{DeliveryMode: db.DeliveryWeekly, DigestTime: 9 * 60, DigestWeekday: time.Monday}
+	assert.Equal(t, time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC), lastDigestSlot(weekly, now))
+
+	weekly.DigestWeekday = time.Sunday
+	assert.Equal(t, time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC), lastDigestSlot(weekly, now))
+
+	weekly.DigestTime = 13 * 60
+	assert.Equal(t, time.Date(2026, 10, 11, 13, 0, 0, 0, time.UTC), lastDigestSlot(weekly, now))
+}
+
+func Test_lastDigestSlotInTimezone(t *testing.T) {
+	// 01:00 of Sunday in Moscow
+	now := time.Date(2026, 10, 17, 22, 0, 0, 0, time.UTC)
+
+	daily := db.Preferences{DeliveryMode: db.DeliveryDaily, DigestTime: 30, Timezone: "Europe/Moscow"}
+	assert.Equal(t, time.Date(2026, 10, 17, 21, 30, 0, 0, time.UTC), lastDigestSlot(daily, now).UTC())
+}
+
+func (s *fakeStorage) ReadSubscribers() ([]db.Subscriber, error) {
+	return s.subscribers, nil
+}
+
+func (s *fakeStorage) UpdateLastPost(chatId int64, sinceTime time.Time) error {
+	for i := range s.subscribers {
+		if s.subscribers[i].ChatID == chatId {
+			s.subscribers[i].LastPost = sinceTime
+		}
+	}
+	return nil
+}
+
+func (s *fakeStorage) UpdateLastDigest(chatId int64, sentAt time.Time) error {
+	preferences := s.preferences[chatId]
+	preferences.LastDigestAt = sentAt
+	s.preferences[chatId] = preferences
+	return nil
+}
+
+func (s *fakeStorage) GetDeliveredPost(postId, receiver int64) (db.DeliveredPost, error) {
+	return db.DeliveredPost{}, sql.ErrNoRows
+}
+
+func (s *fakeStorage) IsPostQueued(postId, chatId int64) (bool, error) {
+	return false, nil
+}
+
+func (s *fakeStorage) EnqueueMessages(messages []db.OutboxMessage, now time.Time) error {
+	s.outbox = append(s.outbox, messages...)
+	return nil
+}
+
+func Test_SendDueDigests(t *testing.T) {
+	// Sunday
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	yesterday := now.AddDate(0, 0, -1)
+	daily := db.Preferences{DeliveryMode: db.DeliveryDaily, DigestTime: 9 * 60, LastDigestAt: yesterday.Add(-3 * time.Hour)}
+
+	storage := newFakeStorage()
+	storage.posts = []db.Post{
+		{Id: 1, Link: "https://store.steampowered.com/app/1/", Title: "Witcher", Platform: fetchers.PlatformSteam, PostedAt: now.Add(-2 * time.Hour)},
+		{Id: 2, Link: "https://store.epicgames.com/p/hades", Title: "Hades", Platform: fetchers.PlatformEpic, PostedAt: yesterday},
+		{Id: 3, Link: "https://store.steampowered.com/app/1", Title: "Witcher", Platform: fetchers.PlatformSteam, PostedAt: now.Add(-3 * time.Hour)},
+		{Id: 4, Link: "https://store.steampowered.com/app/2/", Title: "Portal", Platform: fetchers.PlatformSteam, Source: "muted", PostedAt: now.Add(-2 * time.Hour)},
+	}
+	storage.mutedSources = []string{"muted"}
+	for chatId := range int64(4) {
+		storage.subscribers = append(storage.subscribers, db.Subscriber{ChatID: chatId + 1, LastPost: yesterday.Add(-time.Hour)})
+	}
+	storage.subscribers = append(storage.subscribers, db.Subscriber{ChatID: 5, LastPost: now.Add(-time.Hour)})
+	storage.preferences[1] = daily
+	// Got today's digest already
+	sent := daily
+	sent.LastDigestAt = now.Add(-3 * time.Hour)
+	storage.preferences[2] = sent
+	storage.preferences[3] = db.Preferences{DeliveryMode: db.DeliveryInstant}
+	quiet := daily
+	quiet.QuietStart, quiet.QuietEnd = 11*60, 13*60
+	storage.preferences[4] = quiet
+	// Nothing new since the last post
+	storage.preferences[5] = daily
+	b := &Bot{storage: storage, rules: denyTitles{}}
+
+	b.SendDueDigests(now)
+
+	chats := map[int64]bool{}
+	for _, message := range storage.outbox {
+		chats[message.ChatID] = true
+	}
+	assert.Equal(t, map[int64]bool{1: true}, chats)
+	assert.ElementsMatch(t, []int64{1, 2}, storage.outbox[len(storage.outbox)-1].PostIDs)
+
+	lastPosts := map[int64]time.Time{}
+	for _, s := range storage.subscribers {
+		lastPosts[s.ChatID] = s.LastPost
+	}
+	assert.Equal(t, map[int64]time.Time{1: now, 2: yesterday.Add(-time.Hour), 3: yesterday.Add(-time.Hour), 4: yesterday.Add(-time.Hour), 5: now}, lastPosts)
+	assert.Equal(t, now, storage.preferences[1].LastDigestAt)
+	assert.Equal(t, sent.LastDigestAt, storage.preferences[2].LastDigestAt)
+	assert.Equal(t, daily.LastDigestAt, storage.preferences[4].LastDigestAt)
+	assert.Equal(t, now, storage.preferences[5].LastDigestAt)
+
+	// The quiet hours are over
+	storage.outbox = nil
+	b.SendDueDigests(now.Add(time.Hour))
+	assert.NotEmpty(t, storage.outbox)
+	assert.Equal(t, int64(4), storage.outbox[0].ChatID)
+}
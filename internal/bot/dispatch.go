@@ -0,0 +1,62 @@
+package bot
+
+import (
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// chatQueues runs the updates of every chat in its own goroutine, one by one
+// in the order they came. A chat has a goroutine only while it has updates.
+type chatQueues struct {
+	mu     sync.Mutex
+	queues map[int64][]tgbotapi.Update
+	handle func(update tgbotapi.Update)
+}
+
+func newChatQueues(handle func(update tgbotapi.Update)) *chatQueues {
+	return &chatQueues{queues: map[int64][]tgbotapi.Update{}, handle: handle}
+}
+
+func (q *chatQueues) push(chatId int64, update tgbotapi.Update) {
+	q.mu.Lock()
+	queue, running := q.queues[chatId]
+	q.queues[chatId] = append(queue, update)
+	q.mu.Unlock()
+
+	if !running {
+		go q.run(chatId)
+	}
+}
+
+func (q *chatQueues) run(chatId int64) {
+	for {
+		q.mu.Lock()
+		queue := q.queues[chatId]
+		if len(queue) == 0 {
+			delete(q.queues, chatId)
+			q.mu.Unlock()
+			return
+		}
+		update := queue[0]
+		q.queues[chatId] = queue[1:]
+		q.mu.Unlock()
+
+		q.handle(update)
+	}
+}
+
+// updateChatId returns the chat the update comes from, or the user for the
+// updates without a chat like inline queries
+func updateChatId(update tgbotapi.Update) int64 {
+	switch {
+	case update.Message != nil:
+		return update.Message.Chat.ID
+	case update.CallbackQuery != nil && update.CallbackQuery.Message != nil:
+		return update.CallbackQuery.Message.Chat.ID
+	}
+	if user := update.SentFrom(); user != nil {
+		return user.ID
+	}
+	return 0
+}
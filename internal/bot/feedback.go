@@ -0,0 +1,241 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/freebies-telegram-bot/internal/db"
+	"github.com/freebies-telegram-bot/internal/fetchers"
+	"github.com/freebies-telegram-bot/internal/locale"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// feedback:<status>:<post id>
+	feedbackCallbackPrefix = "feedback:"
+	// hide:<platform>:<post id>
+	hideCallbackPrefix = "hide:"
+	// pick:<post id>
+	pickCallbackPrefix = "pick:"
+
+	// Buttons in a row of a digest keyboard
+	DigestButtonsPerRow = 8
+)
+
+// feedbackKeyboard has the store button and the feedback buttons of a
+// delivered freebie, the chosen status and the hidden platform are marked.
+func feedbackKeyboard(link fetchers.Link, postId int64, status string, hidden bool, lang string) tgbotapi.InlineKeyboardMarkup {
+	rows := [][]tgbotapi.InlineKeyboardButton{}
+	if keyboard := linkKeyboard(link, lang); keyboard != nil {
+		rows = append(rows, keyboard.InlineKeyboard...)
+	}
+
+	claimed := locale.Get(lang, locale.ClaimedButton)
+	notInterested := locale.Get(lang, locale.NotInterestedButton)
+	switch status {
+	case db.FeedbackClaimed:
+		claimed = selectedButton(claimed)
+	case db.FeedbackNotInterested:
+		notInterested = selectedButton(notInterested)
+	}
+	id := strconv.FormatInt(postId, 10)
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(claimed, feedbackCallbackPrefix+db.FeedbackClaimed+":"+id),
+		tgbotapi.NewInlineKeyboardButtonData(notInterested, feedbackCallbackPrefix+db.FeedbackNotInterested+":"+id),
+	))
+
+	if name, ok := fetchers.PlatformNames[link.Platform]; ok && link.Platform != fetchers.PlatformOther {
+		label := locale.Get(lang, locale.HidePlatformButton, name)
+		if hidden {
+			label = locale.Get(lang, locale.ShowPlatformButton, name)
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, hideCallbackPrefix+link.Platform+":"+id),
+		))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// digestKeyboard has a button per link of a digest message numbered like the
+// list, first is the index of the first link. A tap sends the freebie alone
+// with its feedback buttons.
+func digestKeyboard(postIds []int64, first int) *tgbotapi.InlineKeyboardMarkup {
+	rows := [][]tgbotapi.InlineKeyboardButton{}
+	for i, postId := range postIds {
+		if postId == 0 {
+			continue
+		}
+		button := tgbotapi.NewInlineKeyboardButtonData(strconv.Itoa(first+i+1), pickCallbackPrefix+strconv.FormatInt(postId, 10))
+		if len(rows) == 0 || len(rows[len(rows)-1]) == DigestButtonsPerRow {
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow())
+		}
+		rows[len(rows)-1] = append(rows[len(rows)-1], button)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return &keyboard
+}
+
+func selectedButton(label string) string {
+	return "· " + label + " ·"
+}
+
+// parseFeedbackData splits "<prefix><value>:<post id>"
+func parseFeedbackData(data, prefix string) (string, int64, error) {
+	value, id, ok := strings.Cut(strings.TrimPrefix(data, prefix), ":")
+	if !ok {
+		return "", 0, fmt.Errorf("Invalid callback data '%s'", data)
+	}
+	postId, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("Invalid callback data '%s': %w", data, err)
+	}
+	return value, postId, nil
+}
+
+// handleFeedback handles a tap on Claimed or Not interested, tapping the
+// chosen button again clears the choice.
+func (b *Bot) handleFeedback(query *tgbotapi.CallbackQuery) {
+	chatId := query.Message.Chat.ID
+	status, postId, err := parseFeedbackData(query.Data, feedbackCallbackPrefix)
+	if err != nil || (status != db.FeedbackClaimed && status != db.FeedbackNotInterested) {
+		log.Printf("Unknown feedback '%s' from chat id '%d'", query.Data, chatId)
+		return
+	}
+
+	post, err := b.storage.GetPost(postId)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	feedback, err := b.storage.GetFeedback(chatId, postId)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	lang := b.language(chatId)
+	text := ""
+	if feedback.Status == status {
+		err = b.storage.DeleteFeedback(chatId, postId)
+		status = ""
+		text = locale.Get(lang, locale.FeedbackCleared)
+	} else {
+		err = b.storage.StoreFeedback(chatId, post, status)
+		text = locale.Get(lang, locale.FeedbackSaved)
+	}
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	preferences, err := b.storage.GetPreferences(chatId)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	b.answerFeedback(query, post, status, slices.Contains(preferences.HiddenPlatforms, post.Platform), text)
+}
+
+// handleHidePlatform hides the platform of the freebie for the chat, or
+// shows it again
+func (b *Bot) handleHidePlatform(query *tgbotapi.CallbackQuery) {
+	chatId := query.Message.Chat.ID
+	platform, postId, err := parseFeedbackData(query.Data, hideCallbackPrefix)
+	if _, ok := fetchers.PlatformNames[platform]; err != nil || !ok {
+		log.Printf("Unknown platform '%s' in callback from chat id '%d'", query.Data, chatId)
+		return
+	}
+
+	post, err := b.storage.GetPost(postId)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	hidden, text, err := b.toggleHiddenPlatform(chatId, platform)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	feedback, err := b.storage.GetFeedback(chatId, postId)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	b.answerFeedback(query, post, feedback.Status, slices.Contains(hidden, platform), text)
+}
+
+// handlePickPost sends a freebie picked from a digest with its feedback buttons
+func (b *Bot) handlePickPost(query *tgbotapi.CallbackQuery) {
+	chatId := query.Message.Chat.ID
+	postId, err := strconv.ParseInt(strings.TrimPrefix(query.Data, pickCallbackPrefix), 10, 64)
+	if err != nil {
+		log.Printf("Unknown post '%s' in callback from chat id '%d'", query.Data, chatId)
+		return
+	}
+
+	preferences, err := b.storage.GetPreferences(chatId)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	lang := preferredLanguage(preferences)
+	post, err := b.storage.GetPost(postId)
+	if err != nil {
+		log.Println(err)
+		b.answerCallback(query, locale.Get(lang, locale.PostGone))
+		return
+	}
+	feedback, err := b.storage.GetFeedback(chatId, postId)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	link := postToLink(post)
+	keyboard := feedbackKeyboard(link, post.Id, feedback.Status, slices.Contains(preferences.HiddenPlatforms, post.Platform), lang)
+	now := time.Now().In(userLocation(preferences))
+	b.sendMessages(chatId, []db.OutboxMessage{htmlMessage(chatId, formatLink(link, lang, now), &keyboard)})
+	b.answerCallback(query, "")
+}
+
+func (b *Bot) answerFeedback(query *tgbotapi.CallbackQuery, post db.Post, status string, hidden bool, text string) {
+	chatId := query.Message.Chat.ID
+	keyboard := feedbackKeyboard(postToLink(post), post.Id, status, hidden, b.language(chatId))
+	edit := tgbotapi.NewEditMessageReplyMarkup(chatId, query.Message.MessageID, keyboard)
+	if _, err := b.botApi.Send(edit); err != nil {
+		log.Println(err)
+	}
+	if _, err := b.botApi.Request(tgbotapi.NewCallback(query.ID, text)); err != nil {
+		log.Println(err)
+	}
+}
+
+// SendClaimed lists the freebies the chat has claimed, latest first
+func (b *Bot) SendClaimed(chatId int64) error {
+	feedbacks, err := b.storage.ReadFeedbacks(chatId, db.FeedbackClaimed)
+	if err != nil {
+		return err
+	}
+	if len(feedbacks) == 0 {
+		return b.SendText(chatId, locale.NoClaimed)
+	}
+
+	links := make([]fetchers.Link, 0, len(feedbacks))
+	for _, feedback := range feedbacks {
+		links = append(links, fetchers.Link{Link: feedback.Link, Title: feedback.Title})
+	}
+	lang := b.language(chatId)
+	for _, part := range formatDigest(locale.Get(lang, locale.ClaimedHeader), links, lang, time.Now().UTC()) {
+		if err := b.SendHTML(chatId, part.text, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
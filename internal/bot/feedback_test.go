@@ -0,0 +1,105 @@
+package bot
+
+import (
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/freebies-telegram-bot/internal/db"
+	"github.com/freebies-telegram-bot/internal/fetchers"
+	"github.com/freebies-telegram-bot/internal/locale"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_feedbackKeyboard(t *testing.T) {
+	link := fetchers.Link{Link: "https://store.steampowered.com/app/1/", Platform: fetchers.PlatformSteam}
+
+	keyboard := feedbackKeyboard(link, 42, db.FeedbackClaimed, false, locale.English)
+	require.Len(t, keyboard.InlineKeyboard, 3)
+	assert.Equal(t, link.Link, *keyboard.InlineKeyboard[0][0].URL)
+	assert.Equal(t, "· ✅ Claimed ·", keyboard.InlineKeyboard[1][0].Text)
+	assert.Equal(t, "feedback:claimed:42", *keyboard.InlineKeyboard[1][0].CallbackData)
+	assert.Equal(t, "🙈 Not interested", keyboard.InlineKeyboard[1][1].Text)
+	assert.Equal(t, "🚫 Hide Steam", keyboard.InlineKeyboard[2][0].Text)
+	assert.Equal(t, "hide:steam:42", *keyboard.InlineKeyboard[2][0].CallbackData)
+
+	keyboard = feedbackKeyboard(link, 42, "", true, locale.English)
+	assert.Equal(t, "👀 Show Steam", keyboard.InlineKeyboard[2][0].Text)
+
+	// Self posts have no store page and no known platform
+	keyboard = feedbackKeyboard(fetchers.Link{Link: "/r/FreeGameFindings/comments/1/", Platform: fetchers.PlatformOther}, 42, "", false, locale.English)
+	require.Len(t, keyboard.InlineKeyboard, 1)
+}
+
+func Test_parseFeedbackData(t *testing.T) {
+	status, postId, err := parseFeedbackData("feedback:not_interested:42", feedbackCallbackPrefix)
+	require.NoError(t, err)
+	assert.Equal(t, db.FeedbackNotInterested, status)
+	assert.Equal(t, int64(42), postId)
+
+	_, _, err = parseFeedbackData("hide:steam", hideCallbackPrefix)
+	assert.Error(t, err)
+	_, _, err = parseFeedbackData("hide:steam:abc", hideCallbackPrefix)
+	assert.Error(t, err)
+}
+
+func Test_digestKeyboard(t *testing.T) {
+	assert.Nil(t, digestKeyboard([]int64{0, 0}, 0))
+
+	postIds := make([]int64, DigestButtonsPerRow+2)
+	for i := range postIds {
+		postIds[i] = int64(100 + i)
+	}
+	// The post of the second link is unknown
+	postIds[1] = 0
+
+	keyboard := digestKeyboard(postIds, 40)
+	require.NotNil(t, keyboard)
+	require.Len(t, keyboard.InlineKeyboard, 2)
+	assert.Len(t, keyboard.InlineKeyboard[0], DigestButtonsPerRow)
+	assert.Equal(t, "41", keyboard.InlineKeyboard[0][0].Text)
+	assert.Equal(t, "pick:100", *keyboard.InlineKeyboard[0][0].CallbackData)
+	assert.Equal(t, "43", keyboard.InlineKeyboard[0][1].Text)
+	assert.Equal(t, "50", keyboard.InlineKeyboard[1][0].Text)
+}
+
+func (s *fakeStorage) GetPostByLink(link string) (db.Post, error) {
+	for _, post := range s.posts {
+		if post.Link == link {
+			return post, nil
+		}
+	}
+	return db.Post{}, sql.ErrNoRows
+}
+
+func Test_digestMessages(t *testing.T) {
+	storage := newFakeStorage()
+	links := make([]fetchers.Link, MaxDigestPartLinks+2)
+	for i := range links {
+		links[i] = fetchers.Link{Link: "https://store.steampowered.com/app/" + string(rune('a'+i)) + "/", Title: "Game"}
+		// The post of the first link is gone
+		if i != 0 {
+			storage.posts = append(storage.posts, db.Post{Id: int64(i), Link: links[i].Link})
+		}
+	}
+	b := &Bot{storage: storage}
+
+	messages := b.digestMessages(1, "Header", links, locale.English, time.Now().UTC())
+	require.Len(t, messages, 2)
+	assert.Empty(t, messages[0].PostIDs)
+	assert.Len(t, messages[1].PostIDs, len(links)-1)
+
+	var keyboard tgbotapi.InlineKeyboardMarkup
+	require.NoError(t, json.Unmarshal([]byte(messages[0].ReplyMarkup), &keyboard))
+	assert.Equal(t, "2", keyboard.InlineKeyboard[0][0].Text)
+	assert.Equal(t, "pick:1", *keyboard.InlineKeyboard[0][0].CallbackData)
+	require.NoError(t, json.Unmarshal([]byte(messages[1].ReplyMarkup), &keyboard))
+	assert.Equal(t, "41", keyboard.InlineKeyboard[0][0].Text)
+	assert.Equal(t, "42", keyboard.InlineKeyboard[0][1].Text)
+	for _, message := range messages {
+		assert.True(t, message.DisableWebPagePreview)
+	}
+}
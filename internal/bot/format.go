@@ -0,0 +1,128 @@
+package bot
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/freebies-telegram-bot/internal/fetchers"
+	"github.com/freebies-telegram-bot/internal/locale"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// Telegram refuses longer messages
+	MaxMessageLength = 4096
+	// A digest message lists at most this many links, each one gets a button
+	MaxDigestPartLinks = 40
+)
+
+// digestPart is a message of a digest listing count links
+type digestPart struct {
+	text  string
+	count int
+}
+
+// formatLink renders a freebie as an HTML message in lang
+func formatLink(link fetchers.Link, lang string, now time.Time) string {
+	lines := []string{"<b>" + html.EscapeString(linkTitle(link)) + "</b>"}
+	if details := linkDetails(link); details != "" {
+		lines = append(lines, "🎮 "+details)
+	}
+	if dates := linkDates(link, lang, now); dates != "" {
+		lines = append(lines, "⏳ "+dates)
+	}
+	if link.Permalink != "" {
+		lines = append(lines, fmt.Sprintf(`💬 <a href="%s">%s</a>`, html.EscapeString(link.Permalink), locale.Get(lang, locale.SourceThread)))
+	}
+	if !isWebLink(link.Link) {
+		lines = append(lines, html.EscapeString(link.Link))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatDigest renders the links as numbered lists split into messages
+// fitting the Telegram limit, the header starts the first message.
+func formatDigest(header string, links []fetchers.Link, lang string, now time.Time) []digestPart {
+	messages := []digestPart{}
+	current := digestPart{text: html.EscapeString(header)}
+	for i, link := range links {
+		title := html.EscapeString(linkTitle(link))
+		if isWebLink(link.Link) {
+			title = fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(link.Link), title)
+		}
+		parts := []string{title}
+		if details := linkDetails(link); details != "" {
+			parts = append(parts, details)
+		}
+		if dates := linkDates(link, lang, now); dates != "" {
+			parts = append(parts, dates)
+		}
+		if link.Permalink != "" {
+			parts = append(parts, fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(link.Permalink), locale.Get(lang, locale.Thread)))
+		}
+		item := fmt.Sprintf("%d. %s", i+1, strings.Join(parts, " · "))
+
+		if current.text != "" && (len(current.text)+len(item)+2 > MaxMessageLength || current.count == MaxDigestPartLinks) {
+			messages = append(messages, current)
+			current = digestPart{}
+		}
+		if current.text != "" {
+			current.text += "\n\n"
+		}
+		current.text += item
+		current.count += 1
+	}
+	if current.text != "" {
+		messages = append(messages, current)
+	}
+	return messages
+}
+
+// linkKeyboard has the button opening the store page of the freebie
+func linkKeyboard(link fetchers.Link, lang string) *tgbotapi.InlineKeyboardMarkup {
+	if !isWebLink(link.Link) {
+		return nil
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonURL(locale.Get(lang, locale.GetIt), link.Link)),
+	)
+	return &keyboard
+}
+
+func linkTitle(link fetchers.Link) string {
+	if link.Title != "" {
+		return link.Title
+	}
+	return link.Link
+}
+
+func linkDetails(link fetchers.Link) string {
+	details := []string{}
+	if name, ok := fetchers.PlatformNames[link.Platform]; ok && link.Platform != fetchers.PlatformOther {
+		details = append(details, name)
+	}
+	if name, ok := fetchers.DealTypeNames[link.DealType]; ok && link.DealType != fetchers.DealOther {
+		details = append(details, name)
+	}
+	return strings.Join(details, " · ")
+}
+
+func linkDates(link fetchers.Link, lang string, now time.Time) string {
+	layout := locale.Get(lang, locale.DateLayout)
+	dates := []string{}
+	if link.Date.After(now) {
+		dates = append(dates, locale.Get(lang, locale.Starts, link.Date.In(now.Location()).Format(layout)))
+	}
+	if !link.ExpiresAt.IsZero() {
+		dates = append(dates, locale.Get(lang, locale.Until, link.ExpiresAt.In(now.Location()).Format(layout)))
+	}
+	return strings.Join(dates, ", ")
+}
+
+func isWebLink(link string) bool {
+	u, err := url.Parse(link)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -0,0 +1,73 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/freebies-telegram-bot/internal/fetchers"
+	"github.com/freebies-telegram-bot/internal/locale"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_formatLink(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	link := fetchers.Link{
+		Link:      "https://store.steampowered.com/app/1/?a=1&b=2",
+		Title:     "[Steam] (Game) <Tom & Jerry>",
+		Platform:  fetchers.PlatformSteam,
+		DealType:  fetchers.DealGame,
+		ExpiresAt: time.Date(2026, 10, 22, 15, 0, 0, 0, time.UTC),
+		Permalink: "https://www.reddit.com/r/FreeGameFindings/comments/1/tom_jerry/",
+	}
+
+	assert.Equal(t, strings.Join([]string{
+		"<b>[Steam] (Game) &lt;Tom &amp; Jerry&gt;</b>",
+		"🎮 Steam · Game",
+		"⏳ until 22 Oct 15:00 UTC",
+		`💬 <a href="https://www.reddit.com/r/FreeGameFindings/comments/1/tom_jerry/">Source thread</a>`,
+	}, "\n"), formatLink(link, locale.English, now))
+
+	keyboard := linkKeyboard(link, locale.English)
+	require.NotNil(t, keyboard)
+	assert.Equal(t, link.Link, *keyboard.InlineKeyboard[0][0].URL)
+
+	assert.Nil(t, linkKeyboard(fetchers.Link{Link: "/r/FreeGameFindings/comments/1/"}, locale.English))
+}
+
+func Test_formatDigestSplitsLongMessages(t *testing.T) {
+	now := time.Now().UTC()
+	links := []fetchers.Link{}
+	for range 100 {
+		links = append(links, fetchers.Link{
+			Link:  "https://store.epicgames.com/en-US/p/" + strings.Repeat("a", 50),
+			Title: strings.Repeat("Title ", 10),
+		})
+	}
+
+	messages := formatDigest("Here are some freebies for you 😉", links, locale.English, now)
+	require.Greater(t, len(messages), 1)
+	assert.True(t, strings.HasPrefix(messages[0].text, "Here are some freebies for you 😉\n\n1. <a href="))
+	count := 0
+	for _, message := range messages {
+		assert.LessOrEqual(t, len(message.text), MaxMessageLength)
+		assert.LessOrEqual(t, message.count, MaxDigestPartLinks)
+		count += message.count
+	}
+	assert.Equal(t, len(links), count)
+	assert.Contains(t, messages[len(messages)-1].text, "100. ")
+}
+
+func Test_formatDigestLimitsLinks(t *testing.T) {
+	links := make([]fetchers.Link, MaxDigestPartLinks+1)
+	for i := range links {
+		links[i] = fetchers.Link{Link: "https://a.io/", Title: "A"}
+	}
+
+	messages := formatDigest("Header", links, locale.English, time.Now().UTC())
+	require.Len(t, messages, 2)
+	assert.Equal(t, MaxDigestPartLinks, messages[0].count)
+	assert.Equal(t, 1, messages[1].count)
+	assert.True(t, strings.HasPrefix(messages[1].text, "41. "))
+}
@@ -0,0 +1,143 @@
+package bot
+
+import (
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/freebies-telegram-bot/internal/db"
+	"github.com/freebies-telegram-bot/internal/locale"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Group administrators are cached for this long, so a series of taps on a
+// settings keyboard needs a single getChatMember
+const AdminCacheTime = time.Minute
+
+// Callbacks changing the settings of the whole chat, only administrators may
+// use them in groups. Not interested keeps the freebie from the whole chat
+// like hiding a platform. Anyone may page a search or claim a freebie.
+var settingsCallbackPrefixes = []string{
+	platformCallbackPrefix,
+	languageCallbackPrefix,
+	hideCallbackPrefix,
+	feedbackCallbackPrefix + db.FeedbackNotInterested + ":",
+}
+
+// adminCache keeps whether users administer groups
+type adminCache struct {
+	mu      sync.Mutex
+	entries map[adminKey]adminCacheEntry
+}
+
+type adminKey struct {
+	chatId int64
+	userId int64
+}
+
+type adminCacheEntry struct {
+	admin     bool
+	expiresAt time.Time
+}
+
+func newAdminCache() *adminCache {
+	return &adminCache{entries: map[adminKey]adminCacheEntry{}}
+}
+
+func (c *adminCache) get(key adminKey, now time.Time) (bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || !now.Before(entry.expiresAt) {
+		return false, false
+	}
+	return entry.admin, true
+}
+
+// set also drops the expired entries, so the cache doesn't grow with every user
+func (c *adminCache) set(key adminKey, admin bool, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for k, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = adminCacheEntry{admin, now.Add(AdminCacheTime)}
+}
+
+func isSettingsCallback(data string) bool {
+	return slices.ContainsFunc(settingsCallbackPrefixes, func(prefix string) bool {
+		return strings.HasPrefix(data, prefix)
+	})
+}
+
+// isForOtherBot reports whether the command is addressed to another bot
+// like /today@OtherBot
+func (b *Bot) isForOtherBot(message *tgbotapi.Message) bool {
+	_, username, ok := strings.Cut(message.CommandWithAt(), "@")
+	return ok && !strings.EqualFold(username, b.botApi.Self.UserName)
+}
+
+// isMentioned reports whether the command names the bot explicitly
+func isMentioned(message *tgbotapi.Message) bool {
+	return strings.Contains(message.CommandWithAt(), "@")
+}
+
+// canChangeSettings reports whether the sender of the message may change the
+// settings of the chat. Anonymous administrators send on behalf of the group.
+func (b *Bot) canChangeSettings(message *tgbotapi.Message) bool {
+	if message.SenderChat != nil && message.SenderChat.ID == message.Chat.ID {
+		return true
+	}
+	return b.isAdmin(message.Chat, message.From)
+}
+
+// isAdmin reports whether the user administers the group, everyone is the
+// administrator of their private chat.
+func (b *Bot) isAdmin(chat *tgbotapi.Chat, user *tgbotapi.User) bool {
+	if chat.IsPrivate() {
+		return true
+	}
+	if user == nil {
+		return false
+	}
+
+	key := adminKey{chat.ID, user.ID}
+	now := time.Now()
+	if admin, ok := b.admins.get(key, now); ok {
+		return admin
+	}
+
+	member, err := b.botApi.GetChatMember(tgbotapi.GetChatMemberConfig{
+		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{ChatID: chat.ID, UserID: user.ID},
+	})
+	if err != nil {
+		log.Printf("Unable to get member '%d' of chat id '%d': %s", user.ID, chat.ID, err.Error())
+		return false
+	}
+	admin := member.IsCreator() || member.IsAdministrator()
+	b.admins.set(key, admin, now)
+	return admin
+}
+
+// answerAdminsOnly rejects a tap on a settings keyboard by a group member
+func (b *Bot) answerAdminsOnly(query *tgbotapi.CallbackQuery) {
+	callback := tgbotapi.NewCallback(query.ID, locale.Get(b.language(query.Message.Chat.ID), locale.AdminsOnly))
+	if _, err := b.botApi.Request(callback); err != nil {
+		log.Println(err)
+	}
+}
+
+// migrateChat keeps the subscription of a group upgraded to a supergroup
+func (b *Bot) migrateChat(fromChatId, toChatId int64) {
+	if err := b.storage.MigrateChat(fromChatId, toChatId); err != nil {
+		log.Println(err)
+		return
+	}
+	log.Printf("Chat id '%d' migrated to '%d'", fromChatId, toChatId)
+}
@@ -0,0 +1,115 @@
+package bot
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestBotApi serves the Bot API methods with result, which gets the
+// method name and the request. getMe is answered for the constructor.
+func newTestBotApi(t *testing.T, result func(method string, r *http.Request) any) *tgbotapi.BotAPI {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		method := path.Base(r.URL.Path)
+		var response any = tgbotapi.User{ID: 1, IsBot: true, UserName: "FreebiesBot"}
+		if method != "getMe" {
+			response = result(method, r)
+		}
+		if err, ok := response.(*tgbotapi.Error); ok {
+			json.NewEncoder(w).Encode(map[string]any{"ok": false, "error_code": err.Code, "description": err.Message, "parameters": err.ResponseParameters})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": response})
+	}))
+	t.Cleanup(server.Close)
+
+	botApi, err := tgbotapi.NewBotAPIWithClient("token", server.URL+"/bot%s/%s", server.Client())
+	require.NoError(t, err)
+	return botApi
+}
+
+func commandMessage(text string, chat tgbotapi.Chat) *tgbotapi.Message {
+	command := text
+	for i, c := range text {
+		if c == ' ' {
+			command = text[:i]
+			break
+		}
+	}
+	return &tgbotapi.Message{
+		Text:     text,
+		Chat:     &chat,
+		Entities: []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(command)}},
+	}
+}
+
+func Test_isForOtherBot(t *testing.T) {
+	b := &Bot{botApi: &tgbotapi.BotAPI{Self: tgbotapi.User{UserName: "FreebiesBot"}}}
+	group := tgbotapi.Chat{ID: -1, Type: "supergroup"}
+
+	assert.False(t, b.isForOtherBot(commandMessage("/today", group)))
+	assert.False(t, b.isForOtherBot(commandMessage("/today@freebiesbot", group)))
+	assert.True(t, b.isForOtherBot(commandMessage("/today@OtherBot", group)))
+
+	message := commandMessage("/filter@FreebiesBot add dlc", group)
+	assert.Equal(t, "filter", message.Command())
+	assert.True(t, isMentioned(message))
+	assert.False(t, isMentioned(commandMessage("/filter add dlc", group)))
+}
+
+func Test_isAdmin(t *testing.T) {
+	requests := 0
+	botApi := newTestBotApi(t, func(method string, r *http.Request) any {
+		assert.Equal(t, "getChatMember", method)
+		requests += 1
+		switch r.Form.Get("user_id") {
+		case "2":
+			return tgbotapi.ChatMember{Status: "creator"}
+		case "3":
+			return tgbotapi.ChatMember{Status: "administrator"}
+		}
+		return tgbotapi.ChatMember{Status: "member"}
+	})
+	b := &Bot{botApi: botApi, admins: newAdminCache()}
+	group := &tgbotapi.Chat{ID: -1, Type: "supergroup"}
+
+	assert.True(t, b.isAdmin(&tgbotapi.Chat{ID: 4, Type: "private"}, &tgbotapi.User{ID: 4}))
+	assert.False(t, b.isAdmin(group, nil))
+	assert.Equal(t, 0, requests)
+
+	assert.True(t, b.isAdmin(group, &tgbotapi.User{ID: 2}))
+	assert.True(t, b.isAdmin(group, &tgbotapi.User{ID: 3}))
+	assert.False(t, b.isAdmin(group, &tgbotapi.User{ID: 4}))
+	assert.Equal(t, 3, requests)
+
+	// Cached
+	assert.True(t, b.isAdmin(group, &tgbotapi.User{ID: 3}))
+	assert.False(t, b.isAdmin(group, &tgbotapi.User{ID: 4}))
+	assert.Equal(t, 3, requests)
+
+	// Anonymous administrators
+	message := commandMessage("/receive", *group)
+	message.SenderChat = group
+	assert.True(t, b.canChangeSettings(message))
+	message = commandMessage("/receive", *group)
+	message.From = &tgbotapi.User{ID: 4}
+	assert.False(t, b.canChangeSettings(message))
+	assert.Equal(t, 3, requests)
+}
+
+func Test_isSettingsCallback(t *testing.T) {
+	assert.True(t, isSettingsCallback(platformCallbackPrefix+"steam"))
+	assert.True(t, isSettingsCallback(languageCallbackPrefix+"ru"))
+	assert.True(t, isSettingsCallback(hideCallbackPrefix+"steam:1"))
+	assert.True(t, isSettingsCallback(feedbackCallbackPrefix+"not_interested:1"))
+	assert.False(t, isSettingsCallback(feedbackCallbackPrefix+"claimed:1"))
+	assert.False(t, isSettingsCallback(searchCallbackPrefix+"2"))
+	assert.False(t, isSettingsCallback(broadcastCallbackPrefix+"send"))
+}
@@ -0,0 +1,129 @@
+package bot
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/freebies-telegram-bot/internal/locale"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// Telegram accepts at most 50 results
+	InlineResultsLimit = 50
+	// Results are cached by the bot and by Telegram for this long
+	InlineCacheTime = 5 * time.Minute
+	// Posts older than this are not searched
+	InlineSearchPeriod = 30 * 24 * time.Hour
+)
+
+// inlineCache keeps the results of inline queries by language and query text
+type inlineCache struct {
+	mu      sync.Mutex
+	entries map[string]inlineCacheEntry
+}
+
+type inlineCacheEntry struct {
+	results   []any
+	expiresAt time.Time
+}
+
+func newInlineCache() *inlineCache {
+	return &inlineCache{entries: map[string]inlineCacheEntry{}}
+}
+
+func (c *inlineCache) get(key string, now time.Time) ([]any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || !now.Before(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.results, true
+}
+
+// set also drops the expired entries, so the cache doesn't grow with every query
+func (c *inlineCache) set(key string, results []any, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for k, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = inlineCacheEntry{results, now.Add(InlineCacheTime)}
+}
+
+// handleInlineQuery answers "@bot text" with the current freebies having
+// the text in their title or link, ready to be shared.
+func (b *Bot) handleInlineQuery(query *tgbotapi.InlineQuery) {
+	lang := locale.Default
+	if query.From != nil {
+		if matched := locale.Match(query.From.LanguageCode); matched != "" {
+			lang = matched
+		}
+	}
+	text := strings.ToLower(strings.TrimSpace(query.Query))
+	key := lang + ":" + text
+	now := time.Now().UTC()
+
+	results, ok := b.inlineCache.get(key, now)
+	if !ok {
+		var err error
+		results, err = b.searchInlineResults(text, lang, now)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		b.inlineCache.set(key, results, now)
+	}
+
+	answer := tgbotapi.InlineConfig{
+		InlineQueryID: query.ID,
+		Results:       results,
+		CacheTime:     int(InlineCacheTime.Seconds()),
+	}
+	if _, err := b.botApi.Request(answer); err != nil {
+		log.Printf("Unable to answer inline query '%s': %s", query.Query, err.Error())
+	}
+}
+
+func (b *Bot) searchInlineResults(text, lang string, now time.Time) ([]any, error) {
+	posts, err := b.storage.SearchPosts(text, now.Add(-InlineSearchPeriod), now, InlineResultsLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []any{}
+	for _, post := range b.dropMutedPosts(mergePosts(posts)) {
+		link := postToLink(post)
+		if !b.rules.IsAllowed(link) {
+			continue
+		}
+
+		article := tgbotapi.NewInlineQueryResultArticleHTML(strconv.FormatInt(post.Id, 10), linkTitle(link), formatLink(link, lang, now))
+		article.ReplyMarkup = linkKeyboard(link, lang)
+		article.Description = strings.Join(nonEmpty(linkDetails(link), linkDates(link, lang, now)), "\n")
+		if isWebLink(link.Link) {
+			article.URL = link.Link
+			article.HideURL = true
+		}
+		results = append(results, article)
+	}
+	return results, nil
+}
+
+func nonEmpty(values ...string) []string {
+	result := []string{}
+	for _, value := range values {
+		if value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
@@ -0,0 +1,54 @@
+package bot
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/freebies-telegram-bot/internal/db"
+	"github.com/freebies-telegram-bot/internal/locale"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func (s *fakeStorage) SearchPosts(text string, sinceTime, now time.Time, limit int) ([]db.Post, error) {
+	return slices.Clone(s.posts), nil
+}
+
+func Test_inlineCache(t *testing.T) {
+	cache := newInlineCache()
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	_, ok := cache.get("en:witcher", now)
+	assert.False(t, ok)
+
+	cache.set("en:witcher", []any{"result"}, now)
+	results, ok := cache.get("en:witcher", now.Add(InlineCacheTime-time.Second))
+	assert.True(t, ok)
+	assert.Equal(t, []any{"result"}, results)
+
+	_, ok = cache.get("en:witcher", now.Add(InlineCacheTime))
+	assert.False(t, ok)
+
+	cache.set("en:portal", []any{}, now.Add(InlineCacheTime))
+	assert.Len(t, cache.entries, 1)
+}
+
+func Test_searchInlineResults(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	storage := newFakeStorage()
+	storage.posts = []db.Post{
+		{Id: 1, Link: "https://store.steampowered.com/app/1/", Title: "Witcher", PostedAt: now.Add(-time.Hour)},
+		{Id: 2, Link: "https://store.steampowered.com/app/1", Title: "Witcher", PostedAt: now.Add(-2 * time.Hour)},
+		{Id: 3, Link: "https://store.steampowered.com/app/2/", Title: "Witcher 2", Source: "muted", PostedAt: now.Add(-time.Hour)},
+		{Id: 4, Link: "https://store.steampowered.com/app/3/", Title: "Soundtrack", PostedAt: now.Add(-time.Hour)},
+	}
+	storage.mutedSources = []string{"muted"}
+	b := &Bot{storage: storage, rules: denyTitles{"Soundtrack"}}
+
+	results, err := b.searchInlineResults("witcher", locale.English, now)
+	assert.NoError(t, err)
+	if assert.Len(t, results, 1) {
+		assert.Equal(t, "1", results[0].(tgbotapi.InlineQueryResultArticle).ID)
+	}
+}
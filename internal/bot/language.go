@@ -0,0 +1,96 @@
+package bot
+
+import (
+	"log"
+	"strings"
+
+	"github.com/freebies-telegram-bot/internal/db"
+	"github.com/freebies-telegram-bot/internal/locale"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const languageCallbackPrefix = "language:"
+
+func preferredLanguage(preferences db.Preferences) string {
+	if preferences.Language == "" {
+		return locale.Default
+	}
+	return preferences.Language
+}
+
+// language returns the language the chat is spoken to in
+func (b *Bot) language(chatId int64) string {
+	preferences, err := b.storage.GetPreferences(chatId)
+	if err != nil {
+		log.Println(err)
+		return locale.Default
+	}
+	return preferredLanguage(preferences)
+}
+
+// SendText sends the message of the catalog in the language of the chat
+func (b *Bot) SendText(chatId int64, key locale.Key, args ...any) error {
+	return b.SendMsg(chatId, locale.Get(b.language(chatId), key, args...))
+}
+
+// detectLanguage stores the language of the Telegram client of the user
+// for the chats that haven't chosen one yet.
+func (b *Bot) detectLanguage(chatId int64, user *tgbotapi.User) {
+	if user == nil {
+		return
+	}
+	language := locale.Match(user.LanguageCode)
+	if language == "" {
+		return
+	}
+
+	preferences, err := b.storage.GetPreferences(chatId)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	if preferences.Language != "" {
+		return
+	}
+	if err = b.storage.UpdateLanguage(chatId, language); err != nil {
+		log.Println(err)
+	}
+}
+
+func (b *Bot) SendLanguageKeyboard(chatId int64) error {
+	msg := tgbotapi.NewMessage(chatId, locale.Get(b.language(chatId), locale.LanguagePrompt))
+	msg.ReplyMarkup = languageKeyboard()
+	_, err := b.send(chatId, msg)
+	return err
+}
+
+// setLanguage handles a tap on the /language keyboard
+func (b *Bot) setLanguage(query *tgbotapi.CallbackQuery) {
+	chatId := query.Message.Chat.ID
+	language := strings.TrimPrefix(query.Data, languageCallbackPrefix)
+	if _, ok := locale.LanguageNames[language]; !ok {
+		log.Printf("Unknown language '%s' in callback from chat id '%d'", language, chatId)
+		return
+	}
+
+	if err := b.storage.UpdateLanguage(chatId, language); err != nil {
+		log.Println(err)
+		return
+	}
+
+	edit := tgbotapi.NewEditMessageText(chatId, query.Message.MessageID, locale.Get(language, locale.LanguageSet))
+	if _, err := b.botApi.Send(edit); err != nil {
+		log.Println(err)
+	}
+	if _, err := b.botApi.Request(tgbotapi.NewCallback(query.ID, "")); err != nil {
+		log.Println(err)
+	}
+}
+
+func languageKeyboard() tgbotapi.InlineKeyboardMarkup {
+	row := []tgbotapi.InlineKeyboardButton{}
+	for _, language := range locale.Languages {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(locale.LanguageNames[language], languageCallbackPrefix+language))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(row)
+}
@@ -0,0 +1,97 @@
+package bot
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// Telegram allows about 30 messages per second in total
+	GlobalSendRate  = 30
+	GlobalSendBurst = 5
+	// and a message per second to a chat, 20 messages per minute to a group
+	PrivateChatInterval = time.Second
+	GroupChatInterval   = 3 * time.Second
+)
+
+// sendLimiter paces the messages to Telegram with a global token bucket and
+// a minimal interval between the messages to the same chat. Sends reserve
+// their slot, so they go out in the order they came. A message held back by
+// its chat keeps the token it got, so that it doesn't hold up other chats.
+type sendLimiter struct {
+	mu sync.Mutex
+
+	// Token bucket as a virtual schedule: the bucket is empty until
+	// globalNext, a token is added every interval and at most burst are kept
+	interval   time.Duration
+	burst      int
+	globalNext time.Time
+
+	chatNext map[int64]time.Time
+}
+
+func newSendLimiter(rate, burst int) *sendLimiter {
+	return &sendLimiter{
+		interval: time.Second / time.Duration(rate),
+		burst:    burst,
+		chatNext: map[int64]time.Time{},
+	}
+}
+
+// reserve books the next free slot for a message to the chat and returns
+// when it may be sent
+func (l *sendLimiter) reserve(chatId int64, now time.Time) time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	at := now
+	if tolerance := l.interval * time.Duration(l.burst-1); l.globalNext.Add(-tolerance).After(at) {
+		at = l.globalNext.Add(-tolerance)
+	}
+	l.globalNext = maxTime(l.globalNext, at).Add(l.interval)
+
+	if next, ok := l.chatNext[chatId]; ok && next.After(at) {
+		at = next
+	}
+	l.chatNext[chatId] = at.Add(chatInterval(chatId))
+	l.forgetIdleChats(now)
+	return at
+}
+
+// backoff holds the messages to the chat as asked by a 429 response
+func (l *sendLimiter) backoff(chatId int64, until time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if until.After(l.chatNext[chatId]) {
+		l.chatNext[chatId] = until
+	}
+}
+
+// forgetIdleChats drops the chats that can get a message right away, so the
+// map holds only the chats messaged within the last seconds
+func (l *sendLimiter) forgetIdleChats(now time.Time) {
+	if len(l.chatNext) < 1024 {
+		return
+	}
+	for chatId, next := range l.chatNext {
+		if !next.After(now) {
+			delete(l.chatNext, chatId)
+		}
+	}
+}
+
+// Groups and channels have negative ids
+func chatInterval(chatId int64) time.Duration {
+	if chatId < 0 {
+		return GroupChatInterval
+	}
+	return PrivateChatInterval
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,48 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_sendLimiterGlobalRate(t *testing.T) {
+	limiter := newSendLimiter(10, 3)
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	// The burst goes out right away, then a message every 100ms
+	slots := []time.Time{}
+	for chatId := range int64(6) {
+		slots = append(slots, limiter.reserve(chatId, now))
+	}
+	assert.Equal(t, []time.Time{
+		now,
+		now,
+		now,
+		now.Add(100 * time.Millisecond),
+		now.Add(200 * time.Millisecond),
+		now.Add(300 * time.Millisecond),
+	}, slots)
+
+	// The bucket refills while idle
+	later := now.Add(time.Minute)
+	assert.Equal(t, later, limiter.reserve(10, later))
+	assert.Equal(t, later, limiter.reserve(11, later))
+}
+
+func Test_sendLimiterChatInterval(t *testing.T) {
+	limiter := newSendLimiter(1000, 1000)
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, now, limiter.reserve(1, now))
+	assert.Equal(t, now.Add(PrivateChatInterval), limiter.reserve(1, now))
+	assert.Equal(t, now.Add(2*PrivateChatInterval), limiter.reserve(1, now))
+	assert.Equal(t, now, limiter.reserve(2, now))
+
+	assert.Equal(t, now, limiter.reserve(-100, now))
+	assert.Equal(t, now.Add(GroupChatInterval), limiter.reserve(-100, now))
+
+	limiter.backoff(2, now.Add(30*time.Second))
+	assert.Equal(t, now.Add(30*time.Second), limiter.reserve(2, now))
+}
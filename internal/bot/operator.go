@@ -0,0 +1,308 @@
+package bot
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/freebies-telegram-bot/internal/db"
+	"github.com/freebies-telegram-bot/internal/fetchers"
+	"github.com/freebies-telegram-bot/internal/locale"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	broadcastCallbackPrefix = "broadcast:"
+	broadcastSend           = broadcastCallbackPrefix + "send"
+	broadcastCancel         = broadcastCallbackPrefix + "cancel"
+
+	// Telegram limits messages to 4096 characters, the rest of the error is cut
+	maxFetchErrorLength = 3000
+	operatorTimeLayout  = "2006-01-02 15:04 MST"
+)
+
+// broadcastKey is the preview message of a broadcast
+type broadcastKey struct {
+	chatId    int64
+	messageId int
+}
+
+// broadcasts keeps the texts previewed by the operators until they are
+// confirmed or cancelled
+type broadcasts struct {
+	mu    sync.Mutex
+	texts map[broadcastKey]string
+}
+
+func newBroadcasts() *broadcasts {
+	return &broadcasts{texts: map[broadcastKey]string{}}
+}
+
+func (bs *broadcasts) add(key broadcastKey, text string) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	bs.texts[key] = text
+}
+
+// take removes the text, so a broadcast is sent once
+func (bs *broadcasts) take(key broadcastKey) (string, bool) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	text, ok := bs.texts[key]
+	delete(bs.texts, key)
+	return text, ok
+}
+
+// isOperator reports whether the chat is one of the operator chats
+// configured for the bot
+func (b *Bot) isOperator(chatId int64) bool {
+	return slices.Contains(b.operators, chatId)
+}
+
+// operatorTime formats t in the timezone of the chat
+func (b *Bot) operatorTime(chatId int64, t time.Time) string {
+	return t.In(b.localNow(chatId).Location()).Format(operatorTimeLayout)
+}
+
+func (b *Bot) SendStats(chatId int64) error {
+	stats, err := b.storage.ReadStats(time.Now().Add(-24 * time.Hour))
+	if err != nil {
+		return err
+	}
+	return b.SendText(chatId, locale.StatsReport,
+		stats.Subscribers,
+		stats.PostsFound,
+		stats.Fetches, stats.FailedFetches,
+		stats.MessagesSent, stats.MessagesFailed,
+		stats.MessagesPending,
+	)
+}
+
+func (b *Bot) SendSubscriberStats(chatId int64) error {
+	stats, err := b.storage.ReadSubscriberStats(time.Now().AddDate(0, 0, -7))
+	if err != nil {
+		return err
+	}
+	return b.SendText(chatId, locale.SubscribersReport,
+		stats.Total,
+		stats.Groups,
+		stats.Digests,
+		stats.Events[db.EventSubscribed],
+		stats.Events[db.EventUnsubscribed],
+		stats.Events[db.EventDeactivated],
+	)
+}
+
+// PreviewBroadcast shows the message as subscribers will get it, it is
+// sent once the operator confirms it.
+func (b *Bot) PreviewBroadcast(chatId int64, text string) error {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return b.SendText(chatId, locale.BroadcastUsage)
+	}
+	subscribers, err := b.storage.ReadSubscribers()
+	if err != nil {
+		return err
+	}
+
+	lang := b.language(chatId)
+	if err = b.SendMsg(chatId, locale.Get(lang, locale.BroadcastPreview)); err != nil {
+		return err
+	}
+	msg := tgbotapi.NewMessage(chatId, text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(locale.Get(lang, locale.BroadcastSendButton, len(subscribers)), broadcastSend),
+		tgbotapi.NewInlineKeyboardButtonData(locale.Get(lang, locale.BroadcastCancelButton), broadcastCancel),
+	))
+	preview, err := b.send(chatId, msg)
+	if err != nil {
+		return err
+	}
+	b.broadcasts.add(broadcastKey{chatId, preview.MessageID}, text)
+	return nil
+}
+
+// handleBroadcast handles a tap on the buttons of a broadcast preview
+func (b *Bot) handleBroadcast(query *tgbotapi.CallbackQuery) {
+	chatId := query.Message.Chat.ID
+	if !b.isOperator(chatId) {
+		b.answerCallback(query, locale.Get(b.language(chatId), locale.OperatorsOnly))
+		return
+	}
+
+	lang := b.language(chatId)
+	text, ok := b.broadcasts.take(broadcastKey{chatId, query.Message.MessageID})
+	removeButtons := tgbotapi.NewEditMessageReplyMarkup(chatId, query.Message.MessageID, tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}})
+	if _, err := b.botApi.Request(removeButtons); err != nil {
+		log.Println(err)
+	}
+
+	switch {
+	case !ok:
+		b.answerCallback(query, locale.Get(lang, locale.BroadcastExpired))
+	case query.Data == broadcastCancel:
+		b.answerCallback(query, locale.Get(lang, locale.BroadcastCancelled))
+	default:
+		count, err := b.enqueueBroadcast(text)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		b.answerCallback(query, "")
+		if err = b.SendText(chatId, locale.BroadcastQueued, count); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// enqueueBroadcast queues the text for every subscriber at once
+func (b *Bot) enqueueBroadcast(text string) (int, error) {
+	subscribers, err := b.storage.ReadSubscribers()
+	if err != nil {
+		return 0, err
+	}
+	messages := make([]db.OutboxMessage, 0, len(subscribers))
+	for _, s := range subscribers {
+		messages = append(messages, db.OutboxMessage{ChatID: s.ChatID, Text: text})
+	}
+	if err = b.storage.EnqueueMessages(messages, time.Now()); err != nil {
+		return 0, err
+	}
+	log.Printf("Broadcast queued for %d subscribers", len(messages))
+	return len(messages), nil
+}
+
+func (b *Bot) answerCallback(query *tgbotapi.CallbackQuery, text string) {
+	if _, err := b.botApi.Request(tgbotapi.NewCallback(query.ID, text)); err != nil {
+		log.Println(err)
+	}
+}
+
+// FetchNow wakes WatchNewPosts up instead of waiting for the next fetch
+func (b *Bot) FetchNow(chatId int64) error {
+	select {
+	case b.fetchNow <- struct{}{}:
+	default:
+		// A fetch is asked for already
+	}
+	return b.SendText(chatId, locale.FetchStarted)
+}
+
+// waitForFetch sleeps until the next fetch is due or is asked for by FetchNow
+func (b *Bot) waitForFetch(d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-b.fetchNow:
+	}
+}
+
+func (b *Bot) SendLastFetch(chatId int64) error {
+	fetch, err := b.storage.GetLastFetch()
+	if errors.Is(err, sql.ErrNoRows) {
+		return b.SendText(chatId, locale.NoFetches)
+	} else if err != nil {
+		return err
+	}
+	lang := b.language(chatId)
+	lines := []string{locale.Get(lang, locale.LastFetch, fetch.Id, b.operatorTime(chatId, fetch.CreatedAt))}
+
+	failed, err := b.storage.GetLastFetchError()
+	if errors.Is(err, sql.ErrNoRows) {
+		lines = append(lines, locale.Get(lang, locale.NoFetchErrors))
+	} else if err != nil {
+		return err
+	} else {
+		fetchErr := failed.Error
+		if len(fetchErr) > maxFetchErrorLength {
+			fetchErr = strings.ToValidUTF8(fetchErr[:maxFetchErrorLength], "") + "…"
+		}
+		lines = append(lines, locale.Get(lang, locale.LastFetchError, failed.Id, b.operatorTime(chatId, failed.CreatedAt), fetchErr))
+	}
+	return b.SendMsg(chatId, strings.Join(lines, "\n\n"))
+}
+
+// ToggleSource mutes the source or fetches it again, without a name the
+// sources are listed
+func (b *Bot) ToggleSource(chatId int64, name string) error {
+	muted, err := b.storage.ReadMutedSources()
+	if err != nil {
+		return err
+	}
+	names := []string{}
+	for _, source := range b.sources.Sources() {
+		names = append(names, source.Name)
+	}
+
+	if name == "" {
+		lines := []string{locale.Get(b.language(chatId), locale.SourcesHeader)}
+		for _, source := range names {
+			state := "🔊"
+			if slices.Contains(muted, source) {
+				state = "🔇"
+			}
+			lines = append(lines, state+" "+source)
+		}
+		return b.SendMsg(chatId, strings.Join(lines, "\n"))
+	}
+
+	if !slices.Contains(names, name) {
+		return b.SendText(chatId, locale.SourceUnknown, name, strings.Join(names, ", "))
+	}
+	if slices.Contains(muted, name) {
+		if err = b.storage.UnmuteSource(name); err != nil {
+			return err
+		}
+		log.Printf("Source '%s' unmuted by chat id '%d'", name, chatId)
+		return b.SendText(chatId, locale.SourceUnmuted, name)
+	}
+	if err = b.storage.MuteSource(name); err != nil {
+		return err
+	}
+	log.Printf("Source '%s' muted by chat id '%d'", name, chatId)
+	return b.SendText(chatId, locale.SourceMuted, name)
+}
+
+// activeSources returns the sources that aren't muted
+func (b *Bot) activeSources() []fetchers.Source {
+	sources := b.sources.Sources()
+	muted, err := b.storage.ReadMutedSources()
+	if err != nil {
+		log.Println(err)
+		return sources
+	}
+	return slices.DeleteFunc(sources, func(source fetchers.Source) bool {
+		return slices.Contains(muted, source.Name)
+	})
+}
+
+// dropMutedLinks removes the links found by the muted sources, links of
+// posts stored before their source was known are kept
+func (b *Bot) dropMutedLinks(links []fetchers.Link) []fetchers.Link {
+	muted, err := b.storage.ReadMutedSources()
+	if err != nil {
+		log.Println(err)
+		return links
+	}
+	return slices.DeleteFunc(links, func(link fetchers.Link) bool {
+		return slices.Contains(muted, link.Source)
+	})
+}
+
+// dropMutedPosts removes the posts found by the muted sources like
+// dropMutedLinks
+func (b *Bot) dropMutedPosts(posts []db.Post) []db.Post {
+	muted, err := b.storage.ReadMutedSources()
+	if err != nil {
+		log.Println(err)
+		return posts
+	}
+	return slices.DeleteFunc(posts, func(post db.Post) bool {
+		return slices.Contains(muted, post.Source)
+	})
+}
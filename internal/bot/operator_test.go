@@ -0,0 +1,36 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_broadcasts(t *testing.T) {
+	bs := newBroadcasts()
+	bs.add(broadcastKey{5, 10}, "Maintenance tonight")
+	bs.add(broadcastKey{5, 11}, "Back online")
+
+	_, ok := bs.take(broadcastKey{6, 10})
+	assert.False(t, ok)
+
+	text, ok := bs.take(broadcastKey{5, 10})
+	assert.True(t, ok)
+	assert.Equal(t, "Maintenance tonight", text)
+
+	// A second tap doesn't send it again
+	_, ok = bs.take(broadcastKey{5, 10})
+	assert.False(t, ok)
+
+	text, ok = bs.take(broadcastKey{5, 11})
+	assert.True(t, ok)
+	assert.Equal(t, "Back online", text)
+}
+
+func Test_isOperator(t *testing.T) {
+	b := &Bot{operators: []int64{42, -100123}}
+	assert.True(t, b.isOperator(42))
+	assert.True(t, b.isOperator(-100123))
+	assert.False(t, b.isOperator(7))
+	assert.False(t, (&Bot{}).isOperator(42))
+}
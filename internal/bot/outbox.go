@@ -0,0 +1,208 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/freebies-telegram-bot/internal/db"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	OutboxPollInterval = 5 * time.Second
+	// Pending messages read at once
+	OutboxBatchSize = 100
+	// A message is given up after this many failed sends
+	MaxOutboxAttempts = 8
+	// The delay before the first retry, it doubles with every attempt
+	OutboxRetryDelay    = 30 * time.Second
+	OutboxMaxRetryDelay = time.Hour
+)
+
+// outboxClaimant names this replica on the messages it claims, the host name
+// is the name of the pod in Kubernetes
+var outboxClaimant = claimantName()
+
+func claimantName() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "bot"
+	}
+	return fmt.Sprintf("%s:%d", host, os.Getpid())
+}
+
+// htmlMessage makes an HTML message with an optional inline keyboard, the
+// same way SendHTML sends it
+func htmlMessage(chatId int64, text string, keyboard *tgbotapi.InlineKeyboardMarkup) db.OutboxMessage {
+	message := db.OutboxMessage{
+		ChatID:                chatId,
+		Text:                  text,
+		ParseMode:             tgbotapi.ModeHTML,
+		DisableWebPagePreview: keyboard == nil,
+	}
+	if keyboard != nil {
+		replyMarkup, err := json.Marshal(keyboard)
+		if err != nil {
+			log.Printf("Unable to encode keyboard for chat id '%d': %s", chatId, err.Error())
+		}
+		message.ReplyMarkup = string(replyMarkup)
+	}
+	return message
+}
+
+func messageConfig(message db.OutboxMessage) (tgbotapi.MessageConfig, error) {
+	msg := tgbotapi.NewMessage(message.ChatID, message.Text)
+	msg.ParseMode = message.ParseMode
+	msg.DisableWebPagePreview = message.DisableWebPagePreview
+	if message.ReplyMarkup != "" {
+		var keyboard tgbotapi.InlineKeyboardMarkup
+		if err := json.Unmarshal([]byte(message.ReplyMarkup), &keyboard); err != nil {
+			return msg, err
+		}
+		msg.ReplyMarkup = keyboard
+	}
+	return msg, nil
+}
+
+// enqueue stores the messages in the outbox, WatchOutbox sends them
+func (b *Bot) enqueue(chatId int64, messages []db.OutboxMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	if err := b.storage.EnqueueMessages(messages, time.Now()); err != nil {
+		return err
+	}
+	log.Printf("%d messages queued for: %d", len(messages), chatId)
+	return nil
+}
+
+// sendMessages sends the messages right away, bypassing the outbox
+func (b *Bot) sendMessages(chatId int64, messages []db.OutboxMessage) {
+	for _, message := range messages {
+		msg, err := messageConfig(message)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		if _, err = b.send(chatId, msg); err != nil {
+			log.Printf("Unable to send message to %d: %s", chatId, err.Error())
+			if errors.Is(err, ErrChatUnavailable) {
+				return
+			}
+			continue
+		}
+		freebieDeliveries.Add(float64(len(message.PostIDs)))
+	}
+}
+
+// WatchOutbox sends the pending messages of the outbox. Their posts are
+// marked as delivered only once Telegram has accepted them, the messages
+// left pending on shutdown are sent after the restart.
+func (b *Bot) WatchOutbox(ctx context.Context) {
+	ticker := time.NewTicker(OutboxPollInterval)
+	defer ticker.Stop()
+	for {
+		b.drainOutbox(time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// drainOutbox claims the messages due at now and sends them, so replicas
+// draining the same outbox don't send a message twice. Chats are served
+// concurrently, the messages of a chat one by one in the order they were queued.
+func (b *Bot) drainOutbox(now time.Time) {
+	messages, err := b.storage.ClaimPendingMessages(outboxClaimant, now, OutboxBatchSize)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	chats := []int64{}
+	chatMessages := map[int64][]db.OutboxMessage{}
+	for _, message := range messages {
+		if _, ok := chatMessages[message.ChatID]; !ok {
+			chats = append(chats, message.ChatID)
+		}
+		chatMessages[message.ChatID] = append(chatMessages[message.ChatID], message)
+	}
+
+	var wg sync.WaitGroup
+	for _, chatId := range chats {
+		wg.Go(func() {
+			b.sendPending(chatId, chatMessages[chatId])
+		})
+	}
+	wg.Wait()
+}
+
+func (b *Bot) sendPending(chatId int64, messages []db.OutboxMessage) {
+	for i, message := range messages {
+		msg, err := messageConfig(message)
+		if err != nil {
+			b.failMessage(message, err)
+			continue
+		}
+
+		sent, err := b.send(chatId, msg)
+		if errors.Is(err, ErrChatUnavailable) {
+			for _, message := range messages[i:] {
+				b.failMessage(message, err)
+			}
+			return
+		}
+		if err != nil {
+			log.Printf("Unable to send message '%d' to %d: %s", message.Id, chatId, err.Error())
+			b.retryMessage(message, err)
+			// The following messages of the chat are read again only with
+			// this one, so the chat gets them in order
+			return
+		}
+
+		if err = b.storage.MarkMessageSent(message.Id, sent.MessageID, time.Now()); err != nil {
+			log.Println(err)
+		}
+		freebieDeliveries.Add(float64(len(message.PostIDs)))
+	}
+}
+
+func (b *Bot) retryMessage(message db.OutboxMessage, sendErr error) {
+	if message.Attempts+1 >= MaxOutboxAttempts {
+		b.failMessage(message, sendErr)
+		return
+	}
+	nextAttemptAt := time.Now().Add(outboxBackoff(message.Attempts))
+	if err := b.storage.RetryMessage(message.Id, nextAttemptAt, sendErr.Error()); err != nil {
+		log.Println(err)
+	}
+}
+
+func (b *Bot) failMessage(message db.OutboxMessage, sendErr error) {
+	log.Printf("Giving up message '%d' to %d: %s", message.Id, message.ChatID, sendErr.Error())
+	if err := b.storage.FailMessage(message.Id, sendErr.Error()); err != nil {
+		log.Println(err)
+	}
+}
+
+// outboxBackoff returns the delay before retrying a message that has failed
+// attempts times before
+func outboxBackoff(attempts int) time.Duration {
+	delay := OutboxRetryDelay
+	for range attempts {
+		delay *= 2
+		if delay >= OutboxMaxRetryDelay {
+			return OutboxMaxRetryDelay
+		}
+	}
+	return delay
+}
@@ -0,0 +1,93 @@
+package bot
+
+import (
+	"database/sql"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/freebies-telegram-bot/internal/db"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestSqliteStorage opens a new database with all the tables
+func newTestSqliteStorage(t *testing.T) *db.SqliteStorage {
+	conn, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "db.sqlite3"))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	query, err := os.ReadFile("../../db/migrations/create_tables.sql")
+	require.NoError(t, err)
+	_, err = conn.Exec(string(query))
+	require.NoError(t, err)
+	return db.NewStorage(conn)
+}
+
+func Test_outboxBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, outboxBackoff(0))
+	assert.Equal(t, time.Minute, outboxBackoff(1))
+	assert.Equal(t, 4*time.Minute, outboxBackoff(3))
+	assert.Equal(t, time.Hour, outboxBackoff(7))
+	assert.Equal(t, time.Hour, outboxBackoff(100))
+}
+
+func Test_htmlMessage(t *testing.T) {
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonURL("Get it", "https://store.steampowered.com/app/1")),
+	)
+	message := htmlMessage(5, "<b>Game</b>", &keyboard)
+	assert.False(t, message.DisableWebPagePreview)
+
+	msg, err := messageConfig(message)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), msg.ChatID)
+	assert.Equal(t, "<b>Game</b>", msg.Text)
+	assert.Equal(t, tgbotapi.ModeHTML, msg.ParseMode)
+	assert.Equal(t, keyboard, msg.ReplyMarkup)
+
+	msg, err = messageConfig(htmlMessage(5, "digest", nil))
+	assert.NoError(t, err)
+	assert.True(t, msg.DisableWebPagePreview)
+	assert.Nil(t, msg.ReplyMarkup)
+}
+
+func Test_drainOutboxKeepsChatOrder(t *testing.T) {
+	storage := newTestSqliteStorage(t)
+	var mu sync.Mutex
+	sent := []string{}
+	botApi := newTestBotApi(t, func(method string, r *http.Request) any {
+		text := r.Form.Get("text")
+		if text == "header" {
+			return &tgbotapi.Error{Code: 502, Message: "Bad Gateway"}
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, text)
+		return tgbotapi.Message{MessageID: len(sent), Chat: &tgbotapi.Chat{ID: 1}}
+	})
+	b := &Bot{botApi: botApi, storage: storage, sender: newSender(botApi.Send, newSendLimiter(1000, 1000), 2)}
+
+	now := time.Now()
+	require.NoError(t, b.enqueue(1, []db.OutboxMessage{
+		{ChatID: 1, Text: "header"},
+		{ChatID: 1, Text: "link", PostIDs: []int64{10}},
+	}))
+	require.NoError(t, b.enqueue(2, []db.OutboxMessage{{ChatID: 2, Text: "other chat", PostIDs: []int64{10}}}))
+
+	b.drainOutbox(now.Add(time.Second))
+	b.drainOutbox(now.Add(2 * time.Second))
+	assert.Equal(t, []string{"other chat"}, sent)
+
+	_, err := storage.GetDeliveredPost(10, 2)
+	assert.NoError(t, err)
+	_, err = storage.GetDeliveredPost(10, 1)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	queued, err := storage.IsPostQueued(10, 1)
+	require.NoError(t, err)
+	assert.True(t, queued)
+}
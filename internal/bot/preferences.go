@@ -0,0 +1,237 @@
+package bot
+
+import (
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/freebies-telegram-bot/internal/db"
+	"github.com/freebies-telegram-bot/internal/fetchers"
+	"github.com/freebies-telegram-bot/internal/locale"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const platformCallbackPrefix = "platform:"
+
+func (b *Bot) SendPlatformsKeyboard(chatId int64) error {
+	preferences, err := b.storage.GetPreferences(chatId)
+	if err != nil {
+		return err
+	}
+
+	msg := tgbotapi.NewMessage(chatId, locale.Get(preferredLanguage(preferences), locale.PlatformsPrompt))
+	msg.ReplyMarkup = platformsKeyboard(preferences.HiddenPlatforms)
+	_, err = b.send(chatId, msg)
+	return err
+}
+
+// togglePlatform handles a tap on the /platforms keyboard
+func (b *Bot) togglePlatform(query *tgbotapi.CallbackQuery) {
+	chatId := query.Message.Chat.ID
+	platform := strings.TrimPrefix(query.Data, platformCallbackPrefix)
+	if _, ok := fetchers.PlatformNames[platform]; !ok {
+		log.Printf("Unknown platform '%s' in callback from chat id '%d'", platform, chatId)
+		return
+	}
+
+	hidden, text, err := b.toggleHiddenPlatform(chatId, platform)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	edit := tgbotapi.NewEditMessageReplyMarkup(chatId, query.Message.MessageID, platformsKeyboard(hidden))
+	if _, err = b.botApi.Send(edit); err != nil {
+		log.Println(err)
+	}
+	if _, err = b.botApi.Request(tgbotapi.NewCallback(query.ID, text)); err != nil {
+		log.Println(err)
+	}
+}
+
+// toggleHiddenPlatform hides the platform for the chat or shows it again,
+// it returns the hidden platforms and the text telling what has changed.
+func (b *Bot) toggleHiddenPlatform(chatId int64, platform string) ([]string, string, error) {
+	preferences, err := b.storage.GetPreferences(chatId)
+	if err != nil {
+		return nil, "", err
+	}
+
+	lang := preferredLanguage(preferences)
+	hidden := preferences.HiddenPlatforms
+	text := ""
+	if i := slices.Index(hidden, platform); i >= 0 {
+		hidden = slices.Delete(hidden, i, i+1)
+		text = locale.Get(lang, locale.PlatformOn, fetchers.PlatformNames[platform])
+	} else {
+		hidden = append(hidden, platform)
+		text = locale.Get(lang, locale.PlatformOff, fetchers.PlatformNames[platform])
+	}
+
+	err = b.storage.UpdateHiddenPlatforms(chatId, hidden)
+	if err != nil {
+		return nil, "", err
+	}
+	return hidden, text, nil
+}
+
+func platformsKeyboard(hidden []string) tgbotapi.InlineKeyboardMarkup {
+	rows := [][]tgbotapi.InlineKeyboardButton{}
+	for i, platform := range fetchers.Platforms {
+		label := "✅ " + fetchers.PlatformNames[platform]
+		if slices.Contains(hidden, platform) {
+			label = "❌ " + fetchers.PlatformNames[platform]
+		}
+		button := tgbotapi.NewInlineKeyboardButtonData(label, platformCallbackPrefix+platform)
+		if i%2 == 0 {
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(button))
+		} else {
+			rows[len(rows)-1] = append(rows[len(rows)-1], button)
+		}
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// filterByPreferences drops the links the chat doesn't want to get, including
+// the freebies it isn't interested in, even when posted again.
+func (b *Bot) filterByPreferences(chatId int64, links []fetchers.Link) []fetchers.Link {
+	preferences, err := b.storage.GetPreferences(chatId)
+	if err != nil {
+		log.Println(err)
+		return links
+	}
+	notInterested := map[string]bool{}
+	feedbacks, err := b.storage.ReadFeedbacks(chatId, db.FeedbackNotInterested)
+	if err != nil {
+		log.Println(err)
+	}
+	for _, feedback := range feedbacks {
+		notInterested[linkKey(feedback.Link)] = true
+	}
+
+	filteredLinks := []fetchers.Link{}
+	for _, link := range links {
+		if notInterested[linkKey(link.Link)] {
+			continue
+		}
+		platform := link.Platform
+		if platform == "" {
+			platform = fetchers.PlatformOther
+		}
+		if slices.Contains(preferences.HiddenPlatforms, platform) {
+			continue
+		}
+		if !isLinkAllowedByKeywords(link, preferences.Keywords, preferences.AllowOnly) {
+			continue
+		}
+		filteredLinks = append(filteredLinks, link)
+	}
+	return filteredLinks
+}
+
+// isLinkAllowedByKeywords matches keywords against the title and the url of the
+// link ignoring case. Matching links are muted, or are the only ones allowed in
+// allow only mode. An empty list allows everything.
+func isLinkAllowedByKeywords(link fetchers.Link, keywords []string, allowOnly bool) bool {
+	if len(keywords) == 0 {
+		return true
+	}
+
+	text := strings.ToLower(link.Title + " " + link.Link)
+	for _, word := range keywords {
+		if strings.Contains(text, word) {
+			return allowOnly
+		}
+	}
+	return !allowOnly
+}
+
+func (b *Bot) ToggleBatchLinks(chatId int64) error {
+	preferences, err := b.storage.GetPreferences(chatId)
+	if err != nil {
+		return err
+	}
+
+	err = b.storage.UpdateBatchLinks(chatId, !preferences.BatchLinks)
+	if err != nil {
+		return err
+	}
+	if preferences.BatchLinks {
+		return b.SendText(chatId, locale.BatchOff)
+	}
+	return b.SendText(chatId, locale.BatchOn)
+}
+
+// HandleFilterCommand manages the keywords of the chat, args are the
+// arguments of the /filter command.
+func (b *Bot) HandleFilterCommand(chatId int64, args string) error {
+	action, word, _ := strings.Cut(strings.TrimSpace(args), " ")
+	word = strings.ToLower(strings.TrimSpace(word))
+
+	switch action {
+	case "add":
+		if word == "" {
+			return b.sendFilterUsage(chatId)
+		}
+		if err := b.storage.StoreKeyword(chatId, word); err != nil {
+			return err
+		}
+		return b.SendText(chatId, locale.KeywordAdded, word)
+	case "remove":
+		if word == "" {
+			return b.sendFilterUsage(chatId)
+		}
+		removed, err := b.storage.DeleteKeyword(chatId, word)
+		if err != nil {
+			return err
+		}
+		if !removed {
+			return b.SendText(chatId, locale.KeywordMissing, word)
+		}
+		return b.SendText(chatId, locale.KeywordRemoved, word)
+	case "list":
+		preferences, err := b.storage.GetPreferences(chatId)
+		if err != nil {
+			return err
+		}
+		lang := preferredLanguage(preferences)
+		if len(preferences.Keywords) == 0 {
+			return b.SendMsg(chatId, locale.Get(lang, locale.NoKeywords))
+		}
+		mode := locale.Get(lang, locale.KeywordsMuted)
+		if preferences.AllowOnly {
+			mode = locale.Get(lang, locale.KeywordsAllowed)
+		}
+		return b.SendMsg(chatId, mode+"\n"+strings.Join(preferences.Keywords, "\n"))
+	case "mode":
+		switch word {
+		case "allow":
+			if err := b.storage.UpdateAllowOnly(chatId, true); err != nil {
+				return err
+			}
+			return b.SendText(chatId, locale.ModeAllow)
+		case "deny":
+			if err := b.storage.UpdateAllowOnly(chatId, false); err != nil {
+				return err
+			}
+			return b.SendText(chatId, locale.ModeDeny)
+		}
+	}
+	return b.sendFilterUsage(chatId)
+}
+
+func (b *Bot) sendFilterUsage(chatId int64) error {
+	preferences, err := b.storage.GetPreferences(chatId)
+	if err != nil {
+		return err
+	}
+	return b.SendMsg(chatId, locale.Get(preferredLanguage(preferences), filterUsage(preferences)))
+}
+
+// filterUsage tells what /filter add does in the filter mode of the chat
+func filterUsage(preferences db.Preferences) locale.Key {
+	if preferences.AllowOnly {
+		return locale.FilterUsageAllow
+	}
+	return locale.FilterUsage
+}
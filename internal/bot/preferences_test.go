@@ -0,0 +1,144 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/freebies-telegram-bot/internal/db"
+	"github.com/freebies-telegram-bot/internal/fetchers"
+	"github.com/freebies-telegram-bot/internal/locale"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStorage keeps the preferences in memory, the methods a test doesn't
+// implement panic on the nil BotStorage
+type fakeStorage struct {
+	BotStorage
+	preferences      map[int64]db.Preferences
+	posts            []db.Post
+	feedbacks        []db.Feedback
+	channelsLastPost map[int64]time.Time
+	mutedSources     []string
+	subscribers      []db.Subscriber
+	outbox           []db.OutboxMessage
+}
+
+func newFakeStorage() *fakeStorage {
+	return &fakeStorage{
+		preferences:      map[int64]db.Preferences{},
+		channelsLastPost: map[int64]time.Time{},
+	}
+}
+
+func (s *fakeStorage) ReadFeedbacks(chatId int64, status string) ([]db.Feedback, error) {
+	feedbacks := []db.Feedback{}
+	for _, feedback := range s.feedbacks {
+		if feedback.ChatID == chatId && feedback.Status == status {
+			feedbacks = append(feedbacks, feedback)
+		}
+	}
+	return feedbacks, nil
+}
+
+func (s *fakeStorage) GetPreferences(chatId int64) (db.Preferences, error) {
+	return s.preferences[chatId], nil
+}
+
+func (s *fakeStorage) UpdateHiddenPlatforms(chatId int64, platforms []string) error {
+	preferences := s.preferences[chatId]
+	preferences.HiddenPlatforms = platforms
+	s.preferences[chatId] = preferences
+	return nil
+}
+
+func Test_filterByPreferences(t *testing.T) {
+	steam := fetchers.Link{Link: "https://store.steampowered.com/app/1/", Title: "Witcher", Platform: fetchers.PlatformSteam}
+	epic := fetchers.Link{Link: "https://store.epicgames.com/p/hades", Title: "Hades", Platform: fetchers.PlatformEpic}
+	unknown := fetchers.Link{Link: "https://example.com/giveaway", Title: "Giveaway"}
+	links := []fetchers.Link{steam, epic, unknown}
+
+	tests := []struct {
+		name        string
+		preferences db.Preferences
+		want        []fetchers.Link
+	}{
+		{"no preferences", db.Preferences{}, links},
+		{"hidden platform", db.Preferences{HiddenPlatforms: []string{fetchers.PlatformSteam}}, []fetchers.Link{epic, unknown}},
+		{"links without a platform are other", db.Preferences{HiddenPlatforms: []string{fetchers.PlatformOther}}, []fetchers.Link{steam, epic}},
+		{"muted keyword", db.Preferences{Keywords: []string{"hades"}}, []fetchers.Link{steam, unknown}},
+		{"allowed keyword", db.Preferences{Keywords: []string{"hades"}, AllowOnly: true}, []fetchers.Link{epic}},
+		{"platform and keyword", db.Preferences{HiddenPlatforms: []string{fetchers.PlatformEpic}, Keywords: []string{"hades"}, AllowOnly: true}, []fetchers.Link{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := newFakeStorage()
+			storage.preferences[1] = tt.preferences
+			b := &Bot{storage: storage}
+			assert.Equal(t, tt.want, b.filterByPreferences(1, links))
+		})
+	}
+
+	t.Run("not interested", func(t *testing.T) {
+		storage := newFakeStorage()
+		storage.feedbacks = []db.Feedback{
+			{ChatID: 1, Link: "https://www.epicgames.com/p/hades/", Status: db.FeedbackNotInterested},
+			{ChatID: 1, Link: unknown.Link, Status: db.FeedbackClaimed},
+			{ChatID: 2, Link: steam.Link, Status: db.FeedbackNotInterested},
+		}
+		b := &Bot{storage: storage}
+		epic.Link = "https://epicgames.com/p/hades"
+		assert.Equal(t, []fetchers.Link{steam, unknown}, b.filterByPreferences(1, []fetchers.Link{steam, epic, unknown}))
+	})
+}
+
+func Test_toggleHiddenPlatform(t *testing.T) {
+	storage := newFakeStorage()
+	storage.preferences[1] = db.Preferences{HiddenPlatforms: []string{fetchers.PlatformItch}, Language: locale.English}
+	b := &Bot{storage: storage}
+
+	hidden, text, err := b.toggleHiddenPlatform(1, fetchers.PlatformSteam)
+	require.NoError(t, err)
+	assert.Equal(t, []string{fetchers.PlatformItch, fetchers.PlatformSteam}, hidden)
+	assert.Equal(t, "Steam freebies are off", text)
+	assert.Equal(t, hidden, storage.preferences[1].HiddenPlatforms)
+
+	hidden, text, err = b.toggleHiddenPlatform(1, fetchers.PlatformItch)
+	require.NoError(t, err)
+	assert.Equal(t, []string{fetchers.PlatformSteam}, hidden)
+	assert.Equal(t, "itch.io freebies are on", text)
+	assert.Equal(t, hidden, storage.preferences[1].HiddenPlatforms)
+}
+
+func Test_isLinkAllowedByKeywords(t *testing.T) {
+	link := fetchers.Link{Link: "https://store.epicgames.com/en-US/p/hades", Title: "The WITCHER 3 (DLC)"}
+
+	tests := []struct {
+		name      string
+		keywords  []string
+		allowOnly bool
+		want      bool
+	}{
+		{"no keywords", nil, false, true},
+		{"no keywords in allow only mode", nil, true, true},
+		{"muted title word", []string{"dlc"}, false, false},
+		{"allowed title word", []string{"dlc"}, true, true},
+		{"title ignores case", []string{"witcher"}, false, false},
+		{"muted url word", []string{"epicgames"}, false, false},
+		{"allowed url word", []string{"epicgames"}, true, true},
+		{"other word in deny mode", []string{"steam"}, false, true},
+		{"other word in allow only mode", []string{"steam"}, true, false},
+		{"any of the words", []string{"steam", "hades"}, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isLinkAllowedByKeywords(link, tt.keywords, tt.allowOnly))
+		})
+	}
+}
+
+func Test_filterUsage(t *testing.T) {
+	assert.Equal(t, locale.FilterUsage, filterUsage(db.Preferences{}))
+	assert.Equal(t, locale.FilterUsageAllow, filterUsage(db.Preferences{AllowOnly: true}))
+	assert.Contains(t, locale.Get(locale.English, filterUsage(db.Preferences{AllowOnly: true})), "/filter add <word> - get freebies")
+}
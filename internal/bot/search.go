@@ -0,0 +1,146 @@
+package bot
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/freebies-telegram-bot/internal/db"
+	"github.com/freebies-telegram-bot/internal/locale"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	searchCallbackPrefix = "search:"
+
+	SearchPageSize = 10
+)
+
+// Search answers /search with the first page of the stored posts matching
+// text. The answer replies to the command, so the buttons find the text
+// of the search in it.
+func (b *Bot) Search(message *tgbotapi.Message, text string) error {
+	chatId := message.Chat.ID
+	page, keyboard, err := b.searchPage(chatId, text, 0)
+	if err != nil {
+		return err
+	}
+
+	msg := tgbotapi.NewMessage(chatId, page)
+	msg.ParseMode = tgbotapi.ModeHTML
+	msg.DisableWebPagePreview = true
+	msg.ReplyToMessageID = message.MessageID
+	if keyboard != nil {
+		msg.ReplyMarkup = keyboard
+	}
+	_, err = b.send(chatId, msg)
+	return err
+}
+
+// handleSearchPage handles a tap on the buttons of the search results
+func (b *Bot) handleSearchPage(query *tgbotapi.CallbackQuery) {
+	chatId := query.Message.Chat.ID
+	page, err := strconv.Atoi(strings.TrimPrefix(query.Data, searchCallbackPrefix))
+	if err != nil {
+		log.Printf("Invalid search page '%s' from chat id '%d'", query.Data, chatId)
+		return
+	}
+
+	request := query.Message.ReplyToMessage
+	text := ""
+	if request != nil {
+		text = commandArgs(strings.Fields(request.CommandArguments())).String()
+	}
+	if text == "" {
+		// The message with the command is deleted
+		b.answerCallback(query, locale.Get(b.language(chatId), locale.SearchExpired))
+		return
+	}
+
+	results, keyboard, err := b.searchPage(chatId, text, page)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	edit := tgbotapi.NewEditMessageText(chatId, query.Message.MessageID, results)
+	edit.ParseMode = tgbotapi.ModeHTML
+	edit.DisableWebPagePreview = true
+	edit.ReplyMarkup = keyboard
+	if _, err = b.botApi.Send(edit); err != nil {
+		log.Println(err)
+	}
+	b.answerCallback(query, "")
+}
+
+// searchPage renders the page of the results counted from 0 with the
+// buttons to the neighbouring pages
+func (b *Bot) searchPage(chatId int64, text string, page int) (string, *tgbotapi.InlineKeyboardMarkup, error) {
+	preferences, err := b.storage.GetPreferences(chatId)
+	if err != nil {
+		log.Println(err)
+	}
+	lang := preferredLanguage(preferences)
+
+	posts, err := b.storage.SearchPostsHistory(text)
+	if err != nil {
+		return "", nil, err
+	}
+	// Links denied by the rules and the muted sources are left out before
+	// splitting into pages, so the pages are full and the numbers contiguous
+	allowed := []db.Post{}
+	for _, post := range b.dropMutedPosts(mergePosts(posts)) {
+		if b.rules.IsAllowed(postToLink(post)) {
+			allowed = append(allowed, post)
+		}
+	}
+	total := len(allowed)
+	if total == 0 {
+		return html.EscapeString(locale.Get(lang, locale.NoSearchResults, text)), nil, nil
+	}
+
+	pages := (total + SearchPageSize - 1) / SearchPageSize
+	// The results may have shrunk since the buttons were sent
+	page = max(min(page, pages-1), 0)
+	allowed = allowed[page*SearchPageSize : min((page+1)*SearchPageSize, total)]
+	results := formatSearchPage(text, allowed, page, pages, total, lang, userLocation(preferences))
+	return results, searchKeyboard(page, pages, lang), nil
+}
+
+func formatSearchPage(text string, posts []db.Post, page, pages, total int, lang string, location *time.Location) string {
+	lines := []string{html.EscapeString(locale.Get(lang, locale.SearchHeader, total, text, page+1, pages))}
+	for i, post := range posts {
+		link := postToLink(post)
+		title := html.EscapeString(linkTitle(link))
+		if isWebLink(link.Link) {
+			title = fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(link.Link), title)
+		}
+		parts := []string{title}
+		if details := linkDetails(link); details != "" {
+			parts = append(parts, details)
+		}
+		posted := post.PostedAt.In(location).Format(locale.Get(lang, locale.SearchDateLayout))
+		parts = append(parts, locale.Get(lang, locale.Posted, posted))
+		lines = append(lines, fmt.Sprintf("%d. %s", page*SearchPageSize+i+1, strings.Join(parts, " · ")))
+	}
+	return strings.Join(lines, "\n\n")
+}
+
+// searchKeyboard has the buttons to the previous and the next page, nil
+// for a single page
+func searchKeyboard(page, pages int, lang string) *tgbotapi.InlineKeyboardMarkup {
+	row := []tgbotapi.InlineKeyboardButton{}
+	if page > 0 {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(locale.Get(lang, locale.SearchPrevious), searchCallbackPrefix+strconv.Itoa(page-1)))
+	}
+	if page < pages-1 {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(locale.Get(lang, locale.SearchNext), searchCallbackPrefix+strconv.Itoa(page+1)))
+	}
+	if len(row) == 0 {
+		return nil
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(row)
+	return &keyboard
+}
@@ -0,0 +1,101 @@
+package bot
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/freebies-telegram-bot/internal/db"
+	"github.com/freebies-telegram-bot/internal/fetchers"
+	"github.com/freebies-telegram-bot/internal/locale"
+	"github.com/stretchr/testify/assert"
+)
+
+func (s *fakeStorage) SearchPostsHistory(text string) ([]db.Post, error) {
+	return slices.Clone(s.posts), nil
+}
+
+func Test_searchPage(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	storage := newFakeStorage()
+	for i := range 13 {
+		title := fmt.Sprintf("Witcher %d", i)
+		if i < 2 {
+			title = "Soundtrack"
+		}
+		storage.posts = append(storage.posts, db.Post{
+			Id:       int64(i + 1),
+			Link:     fmt.Sprintf("https://store.steampowered.com/app/%d/", i),
+			Title:    title,
+			PostedAt: now.Add(-time.Duration(i) * time.Hour),
+		})
+	}
+	storage.posts = append(storage.posts, db.Post{Id: 14, Link: "https://store.steampowered.com/app/20/", Title: "Witcher 20", Source: "muted", PostedAt: now})
+	storage.mutedSources = []string{"muted"}
+	b := &Bot{storage: storage, rules: denyTitles{"Soundtrack"}}
+
+	first, keyboard, err := b.searchPage(1, "witcher", 0)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(first, "Found 11 freebies for &#34;witcher&#34;, page 1 of 2"))
+	assert.Contains(t, first, "1. <a href=\"https://store.steampowered.com/app/2/\">Witcher 2</a>")
+	assert.Contains(t, first, "10. <a href=\"https://store.steampowered.com/app/11/\">Witcher 11</a>")
+	assert.NotNil(t, keyboard)
+
+	last, _, err := b.searchPage(1, "witcher", 1)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(last, "Found 11 freebies for &#34;witcher&#34;, page 2 of 2"))
+	assert.Contains(t, last, "11. <a href=\"https://store.steampowered.com/app/12/\">Witcher 12</a>")
+	assert.NotContains(t, last, "12.")
+
+	// Fewer results than when the buttons were sent
+	gone, _, err := b.searchPage(1, "witcher", 5)
+	assert.NoError(t, err)
+	assert.Equal(t, last, gone)
+}
+
+func Test_formatSearchPage(t *testing.T) {
+	posts := []db.Post{
+		{
+			Link:     "https://store.epicgames.com/en-US/p/the-witcher-3",
+			Title:    "The Witcher 3 & DLCs",
+			PostedAt: time.Date(2026, 10, 17, 22, 30, 0, 0, time.UTC),
+			Platform: fetchers.PlatformEpic,
+			DealType: fetchers.DealGame,
+		},
+		{
+			Link:     "steam://install/12345",
+			Title:    "Witcher Adventure Game",
+			PostedAt: time.Date(2026, 9, 1, 10, 0, 0, 0, time.UTC),
+		},
+	}
+
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	assert.NoError(t, err)
+	assert.Equal(t, "Found 12 freebies for &#34;witcher &lt;3&#34;, page 2 of 2\n\n"+
+		"11. <a href=\"https://store.epicgames.com/en-US/p/the-witcher-3\">The Witcher 3 &amp; DLCs</a> · Epic Games · Game · posted 18 Oct 2026\n\n"+
+		"12. Witcher Adventure Game · posted 1 Sep 2026",
+		formatSearchPage("witcher <3", posts, 1, 2, 12, locale.English, moscow))
+}
+
+func Test_searchKeyboard(t *testing.T) {
+	assert.Nil(t, searchKeyboard(0, 1, locale.English))
+
+	first := searchKeyboard(0, 3, locale.English)
+	if assert.NotNil(t, first) && assert.Len(t, first.InlineKeyboard[0], 1) {
+		assert.Equal(t, "search:1", *first.InlineKeyboard[0][0].CallbackData)
+	}
+
+	middle := searchKeyboard(1, 3, locale.Russian)
+	if assert.NotNil(t, middle) && assert.Len(t, middle.InlineKeyboard[0], 2) {
+		assert.Equal(t, "◀️ Назад", middle.InlineKeyboard[0][0].Text)
+		assert.Equal(t, "search:0", *middle.InlineKeyboard[0][0].CallbackData)
+		assert.Equal(t, "search:2", *middle.InlineKeyboard[0][1].CallbackData)
+	}
+
+	last := searchKeyboard(2, 3, locale.English)
+	if assert.NotNil(t, last) && assert.Len(t, last.InlineKeyboard[0], 1) {
+		assert.Equal(t, "search:1", *last.InlineKeyboard[0][0].CallbackData)
+	}
+}
@@ -0,0 +1,85 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/freebies-telegram-bot/internal/db"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Sends limited by Telegram are retried this many times
+const MaxSendRetries = 3
+
+// ErrChatUnavailable is returned for the chats that can't get messages
+// anymore, e.g. the user has blocked the bot. Such chats are unsubscribed,
+// such channels are disabled.
+var ErrChatUnavailable = errors.New("Chat is unavailable")
+
+// send sends the message to the chat through the sender and waits for the
+// answer of Telegram. Chats that can't get messages anymore are unsubscribed.
+func (b *Bot) send(chatId int64, c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	start := time.Now()
+	message, err := b.sender.sendMessage(chatId, c)
+	sendLatency.Observe(time.Since(start).Seconds())
+	if err == nil {
+		return message, nil
+	}
+
+	var apiErr *tgbotapi.Error
+	if errors.As(err, &apiErr) && apiErr.MigrateToChatID != 0 {
+		b.migrateChat(chatId, apiErr.MigrateToChatID)
+		return message, err
+	}
+	if reason, ok := permanentFailure(err); ok {
+		if b.isChannel(chatId) {
+			b.disableChannel(chatId, reason)
+		} else {
+			b.deactivateChat(chatId, reason)
+		}
+		return message, fmt.Errorf("%w: %s", ErrChatUnavailable, reason)
+	}
+	return message, err
+}
+
+// retryAfter returns how long Telegram asks to wait on 429 Too Many Requests
+func retryAfter(err error) (time.Duration, bool) {
+	var apiErr *tgbotapi.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != 429 {
+		return 0, false
+	}
+	return time.Duration(max(apiErr.RetryAfter, 1)) * time.Second, true
+}
+
+// permanentFailure returns the reason if the chat will never get messages
+// again: the bot is blocked, kicked from the group or the chat is gone.
+func permanentFailure(err error) (string, bool) {
+	var apiErr *tgbotapi.Error
+	if !errors.As(err, &apiErr) {
+		return "", false
+	}
+	switch {
+	case apiErr.Code == 403:
+		return apiErr.Message, true
+	case apiErr.Code == 400 && strings.Contains(strings.ToLower(apiErr.Message), "chat not found"):
+		return apiErr.Message, true
+	}
+	return "", false
+}
+
+func (b *Bot) deactivateChat(chatId int64, reason string) {
+	log.Printf("Unsubscribing chat id '%d': %s", chatId, reason)
+	if err := b.storage.DeleteSubscriber(int(chatId)); err != nil {
+		log.Println(err)
+	}
+	b.logEvent(chatId, db.EventDeactivated, reason)
+}
+
+func (b *Bot) logEvent(chatId int64, event, reason string) {
+	if err := b.storage.StoreSubscriberEvent(chatId, event, reason); err != nil {
+		log.Println(err)
+	}
+}
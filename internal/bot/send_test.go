@@ -0,0 +1,58 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_classifySendErrors(t *testing.T) {
+	blocked := fmt.Errorf("send: %w", &tgbotapi.Error{Code: 403, Message: "Forbidden: bot was blocked by the user"})
+	reason, ok := permanentFailure(blocked)
+	assert.True(t, ok)
+	assert.Equal(t, "Forbidden: bot was blocked by the user", reason)
+
+	_, ok = permanentFailure(&tgbotapi.Error{Code: 400, Message: "Bad Request: chat not found"})
+	assert.True(t, ok)
+	_, ok = permanentFailure(&tgbotapi.Error{Code: 400, Message: "Bad Request: message is too long"})
+	assert.False(t, ok)
+	_, ok = permanentFailure(errors.New("connection reset by peer"))
+	assert.False(t, ok)
+
+	wait, ok := retryAfter(&tgbotapi.Error{Code: 429, Message: "Too Many Requests: retry after 7", ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 7}})
+	assert.True(t, ok)
+	assert.Equal(t, 7*time.Second, wait)
+	_, ok = retryAfter(&tgbotapi.Error{Code: 403})
+	assert.False(t, ok)
+}
+
+func Test_sendDisablesChannels(t *testing.T) {
+	botApi := newTestBotApi(t, func(method string, r *http.Request) any {
+		return &tgbotapi.Error{Code: 403, Message: "Forbidden: bot is not a member of the channel chat"}
+	})
+	storage := newFakeStorage()
+	b := &Bot{
+		botApi:           botApi,
+		storage:          storage,
+		sender:           newSender(botApi.Send, newSendLimiter(1000, 1000), 1),
+		channels:         []Channel{{ChatID: -100}},
+		operators:        []int64{7},
+		disabledChannels: newDisabledChannels(),
+	}
+
+	// The channel isn't a subscriber, DeleteSubscriber would panic
+	for range 2 {
+		_, err := b.send(-100, tgbotapi.NewMessage(-100, "Witcher"))
+		assert.ErrorIs(t, err, ErrChatUnavailable)
+	}
+	assert.True(t, b.disabledChannels.contains(-100))
+	require.Len(t, storage.outbox, 1)
+	assert.Equal(t, int64(7), storage.outbox[0].ChatID)
+	assert.Contains(t, storage.outbox[0].Text, "-100")
+}
@@ -0,0 +1,140 @@
+package bot
+
+import (
+	"container/heap"
+	"log"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// Requests to Telegram running at the same time
+	SendWorkers = 8
+	// Messages accepted by the sender before its callers have to wait
+	SendQueueSize = 1024
+)
+
+// sender is the only way out to Telegram. Every message books its slot with
+// the limiter and waits in one queue ordered by slot. The scheduler hands the
+// due messages to a pool of workers, so a message held back by its chat
+// doesn't hold up the messages to other chats.
+type sender struct {
+	send    func(c tgbotapi.Chattable) (tgbotapi.Message, error)
+	limiter *sendLimiter
+
+	requests chan *sendRequest
+	due      chan *sendRequest
+	// Only touched by the scheduler
+	queue sendQueue
+	seq   uint64
+}
+
+type sendRequest struct {
+	chatId  int64
+	c       tgbotapi.Chattable
+	at      time.Time
+	seq     uint64
+	attempt int
+	result  chan sendResult
+}
+
+type sendResult struct {
+	message tgbotapi.Message
+	err     error
+}
+
+func newSender(send func(c tgbotapi.Chattable) (tgbotapi.Message, error), limiter *sendLimiter, workers int) *sender {
+	s := &sender{
+		send:     send,
+		limiter:  limiter,
+		requests: make(chan *sendRequest, SendQueueSize),
+		due:      make(chan *sendRequest),
+	}
+	go s.schedule()
+	for range workers {
+		go s.work()
+	}
+	return s
+}
+
+// sendMessage queues the message and waits until Telegram has answered.
+// Flood limits are waited out up to MaxSendRetries times.
+func (s *sender) sendMessage(chatId int64, c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	request := &sendRequest{chatId: chatId, c: c, result: make(chan sendResult, 1)}
+	s.submit(request)
+	result := <-request.result
+	return result.message, result.err
+}
+
+func (s *sender) submit(request *sendRequest) {
+	request.at = s.limiter.reserve(request.chatId, time.Now())
+	sendQueueDepth.Inc()
+	s.requests <- request
+}
+
+// schedule keeps the queued messages ordered by slot and passes each one to
+// the workers once its slot has come
+func (s *sender) schedule() {
+	timer := time.NewTimer(time.Hour)
+	for {
+		var wait <-chan time.Time
+		if s.queue.Len() != 0 {
+			timer.Reset(time.Until(s.queue[0].at))
+			wait = timer.C
+		}
+
+		select {
+		case request := <-s.requests:
+			s.seq += 1
+			request.seq = s.seq
+			heap.Push(&s.queue, request)
+		case <-wait:
+			now := time.Now()
+			for s.queue.Len() != 0 && !s.queue[0].at.After(now) {
+				s.due <- heap.Pop(&s.queue).(*sendRequest)
+			}
+		}
+	}
+}
+
+func (s *sender) work() {
+	for request := range s.due {
+		sendQueueDepth.Dec()
+		message, err := s.send(request.c)
+
+		if wait, ok := retryAfter(err); ok && request.attempt < MaxSendRetries {
+			log.Printf("Too many requests sending to %d, retrying in %s", request.chatId, wait)
+			s.limiter.backoff(request.chatId, time.Now().Add(wait))
+			request.attempt += 1
+			// The scheduler may be waiting for a worker
+			go s.submit(request)
+			continue
+		}
+		request.result <- sendResult{message, err}
+	}
+}
+
+// sendQueue is a heap of the requests by slot, then in the order they came
+type sendQueue []*sendRequest
+
+func (q sendQueue) Len() int { return len(q) }
+
+func (q sendQueue) Less(i, j int) bool {
+	if q[i].at.Equal(q[j].at) {
+		return q[i].seq < q[j].seq
+	}
+	return q[i].at.Before(q[j].at)
+}
+
+func (q sendQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *sendQueue) Push(x any) { *q = append(*q, x.(*sendRequest)) }
+
+func (q *sendQueue) Pop() any {
+	old := *q
+	request := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	return request
+}
@@ -0,0 +1,114 @@
+package bot
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_senderChatsDontWaitForEachOther(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	sent := []int64{}
+	s := newSender(func(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+		chatId := c.(tgbotapi.MessageConfig).ChatID
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, chatId)
+		return tgbotapi.Message{MessageID: len(sent)}, nil
+	}, newSendLimiter(1000, 1000), 2)
+
+	_, err := s.sendMessage(1, tgbotapi.NewMessage(1, "first"))
+	require.NoError(t, err)
+
+	// Held back by the interval of its chat
+	second := make(chan error)
+	go func() {
+		_, err := s.sendMessage(1, tgbotapi.NewMessage(1, "second"))
+		second <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	start := time.Now()
+	_, err = s.sendMessage(2, tgbotapi.NewMessage(2, "other chat"))
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), PrivateChatInterval/2)
+
+	require.NoError(t, <-second)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []int64{1, 2, 1}, sent)
+}
+
+func Test_senderRetriesTooManyRequests(t *testing.T) {
+	t.Parallel()
+	attempts := 0
+	s := newSender(func(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+		attempts += 1
+		if attempts == 1 {
+			return tgbotapi.Message{}, &tgbotapi.Error{Code: 429, ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 1}}
+		}
+		return tgbotapi.Message{MessageID: 7}, nil
+	}, newSendLimiter(1000, 1000), 1)
+
+	start := time.Now()
+	message, err := s.sendMessage(1, tgbotapi.NewMessage(1, "text"))
+	require.NoError(t, err)
+	assert.Equal(t, 7, message.MessageID)
+	assert.Equal(t, 2, attempts)
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+}
+
+func Test_chatQueues(t *testing.T) {
+	var mu sync.Mutex
+	handled := []string{}
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(3)
+	q := newChatQueues(func(update tgbotapi.Update) {
+		defer wg.Done()
+		if update.Message.Text == "slow" {
+			<-release
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		handled = append(handled, update.Message.Text)
+	})
+	message := func(chatId int64, text string) tgbotapi.Update {
+		return tgbotapi.Update{Message: &tgbotapi.Message{Text: text, Chat: &tgbotapi.Chat{ID: chatId}}}
+	}
+
+	q.push(1, message(1, "slow"))
+	q.push(1, message(1, "after slow"))
+	q.push(2, message(2, "other chat"))
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(handled) == 1
+	}, time.Second, 10*time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, []string{"other chat", "slow", "after slow"}, handled)
+
+	// The goroutines of the chats are gone
+	assert.Eventually(t, func() bool {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		return len(q.queues) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func Test_updateChatId(t *testing.T) {
+	chat := &tgbotapi.Chat{ID: -5}
+	user := &tgbotapi.User{ID: 7}
+	assert.Equal(t, int64(-5), updateChatId(tgbotapi.Update{Message: &tgbotapi.Message{Chat: chat, From: user}}))
+	assert.Equal(t, int64(-5), updateChatId(tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{Message: &tgbotapi.Message{Chat: chat}, From: user}}))
+	assert.Equal(t, int64(7), updateChatId(tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{From: user}}))
+	assert.Equal(t, int64(7), updateChatId(tgbotapi.Update{InlineQuery: &tgbotapi.InlineQuery{From: user}}))
+	assert.Equal(t, int64(0), updateChatId(tgbotapi.Update{}))
+}
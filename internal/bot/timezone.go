@@ -0,0 +1,144 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/freebies-telegram-bot/internal/db"
+	"github.com/freebies-telegram-bot/internal/locale"
+)
+
+// userLocation falls back to UTC when the stored timezone is unknown
+func userLocation(preferences db.Preferences) *time.Location {
+	if preferences.Timezone == "" {
+		return time.UTC
+	}
+	location, err := time.LoadLocation(preferences.Timezone)
+	if err != nil {
+		log.Printf("Unknown timezone '%s' of chat id '%d': %s", preferences.Timezone, preferences.ChatID, err.Error())
+		return time.UTC
+	}
+	return location
+}
+
+// localNow is the current time in the timezone of the chat
+func (b *Bot) localNow(chatId int64) time.Time {
+	preferences, err := b.storage.GetPreferences(chatId)
+	if err != nil {
+		log.Println(err)
+		return time.Now().UTC()
+	}
+	return time.Now().In(userLocation(preferences))
+}
+
+// startOfDay returns the midnight of the day of t in its location
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// isQuiet reports whether now falls inside the quiet hours of the chat.
+// The window may span midnight, e.g. 23:00-08:00.
+func isQuiet(preferences db.Preferences, now time.Time) bool {
+	start, end := preferences.QuietStart, preferences.QuietEnd
+	if start == end {
+		return false
+	}
+	local := now.In(userLocation(preferences))
+	minutes := local.Hour()*60 + local.Minute()
+	if start < end {
+		return minutes >= start && minutes < end
+	}
+	return minutes >= start || minutes < end
+}
+
+// parseClock parses "HH:MM" into minutes after midnight
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func formatClock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+// parseTimezone accepts IANA names and whole hour offsets like UTC+3
+func parseTimezone(s string) (string, error) {
+	upper := strings.ToUpper(s)
+	for _, prefix := range []string{"UTC", "GMT"} {
+		offset, ok := strings.CutPrefix(upper, prefix)
+		if !ok || offset == "" {
+			continue
+		}
+		hours, err := strconv.Atoi(offset)
+		if err != nil || hours < -12 || hours > 14 {
+			return "", fmt.Errorf("Invalid offset '%s'", s)
+		}
+		if hours == 0 {
+			return "UTC", nil
+		}
+		// Signs of Etc zones are inverted
+		s = fmt.Sprintf("Etc/GMT%+d", -hours)
+	}
+	location, err := time.LoadLocation(s)
+	if err != nil {
+		return "", err
+	}
+	return location.String(), nil
+}
+
+func (b *Bot) HandleTimezoneCommand(chatId int64, args string) error {
+	args = strings.TrimSpace(args)
+	if args == "" {
+		preferences, err := b.storage.GetPreferences(chatId)
+		if err != nil {
+			return err
+		}
+		lang := preferredLanguage(preferences)
+		return b.SendMsg(chatId, locale.Get(lang, locale.TimezoneCurrent, preferences.Timezone)+"\n\n"+locale.Get(lang, locale.TimezoneUsage))
+	}
+
+	timezone, err := parseTimezone(args)
+	if err != nil {
+		lang := b.language(chatId)
+		return b.SendMsg(chatId, locale.Get(lang, locale.TimezoneUnknown, args)+"\n\n"+locale.Get(lang, locale.TimezoneUsage))
+	}
+	if err = b.storage.UpdateTimezone(chatId, timezone); err != nil {
+		return err
+	}
+	return b.SendText(chatId, locale.TimezoneSet, timezone)
+}
+
+func (b *Bot) HandleQuietCommand(chatId int64, args string) error {
+	args = strings.TrimSpace(args)
+	if args == "off" {
+		if err := b.storage.UpdateQuietHours(chatId, 0, 0); err != nil {
+			return err
+		}
+		return b.SendText(chatId, locale.QuietOff)
+	}
+
+	from, to, ok := strings.Cut(args, "-")
+	if !ok {
+		return b.SendText(chatId, locale.QuietUsage)
+	}
+	start, err := parseClock(strings.TrimSpace(from))
+	if err != nil {
+		return b.SendText(chatId, locale.QuietUsage)
+	}
+	end, err := parseClock(strings.TrimSpace(to))
+	if err != nil || start == end {
+		return b.SendText(chatId, locale.QuietUsage)
+	}
+
+	if err = b.storage.UpdateQuietHours(chatId, start, end); err != nil {
+		return err
+	}
+	return b.SendText(chatId, locale.QuietSet, formatClock(start), formatClock(end))
+}
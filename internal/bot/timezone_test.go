@@ -0,0 +1,51 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/freebies-telegram-bot/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_isQuiet(t *testing.T) {
+	preferences := db.Preferences{Timezone: "Europe/Moscow", QuietStart: 23 * 60, QuietEnd: 8 * 60}
+
+	// 02:00 in Moscow
+	assert.True(t, isQuiet(preferences, time.Date(2026, 10, 18, 23, 0, 0, 0, time.UTC)))
+	// 08:00 in Moscow
+	assert.False(t, isQuiet(preferences, time.Date(2026, 10, 18, 5, 0, 0, 0, time.UTC)))
+	// 22:59 in Moscow
+	assert.False(t, isQuiet(preferences, time.Date(2026, 10, 18, 19, 59, 0, 0, time.UTC)))
+
+	preferences.QuietStart, preferences.QuietEnd = 13*60, 14*60
+	assert.True(t, isQuiet(preferences, time.Date(2026, 10, 18, 10, 30, 0, 0, time.UTC)))
+	assert.False(t, isQuiet(preferences, time.Date(2026, 10, 18, 11, 0, 0, 0, time.UTC)))
+
+	preferences.QuietStart, preferences.QuietEnd = 0, 0
+	assert.False(t, isQuiet(preferences, time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)))
+}
+
+func Test_parseTimezone(t *testing.T) {
+	timezone, err := parseTimezone("America/Los_Angeles")
+	require.NoError(t, err)
+	assert.Equal(t, "America/Los_Angeles", timezone)
+
+	timezone, err = parseTimezone("UTC+3")
+	require.NoError(t, err)
+	assert.Equal(t, "Etc/GMT-3", timezone)
+
+	timezone, err = parseTimezone("utc-8")
+	require.NoError(t, err)
+	assert.Equal(t, "Etc/GMT+8", timezone)
+
+	timezone, err = parseTimezone("UTC")
+	require.NoError(t, err)
+	assert.Equal(t, "UTC", timezone)
+
+	_, err = parseTimezone("Mars/Olympus")
+	assert.Error(t, err)
+	_, err = parseTimezone("UTC+20")
+	assert.Error(t, err)
+}
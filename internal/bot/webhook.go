@@ -0,0 +1,79 @@
+package bot
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// Telegram sends the secret given to setWebhook in this header
+	WebhookSecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+	// Updates waiting for the dispatcher, the webhook requests block above it
+	WebhookQueueSize = 100
+)
+
+// Telegram allows 1-256 characters A-Z, a-z, 0-9, _ and -
+var webhookSecretRe = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+
+// RunWebhook registers the webhook at webhookUrl and handles the updates
+// it receives. WebhookHandler has to be served at the path of webhookUrl.
+func (b *Bot) RunWebhook(webhookUrl, secret string) error {
+	if err := b.SetWebhook(webhookUrl, secret); err != nil {
+		return err
+	}
+	b.dispatch(b.webhookUpdates)
+	return nil
+}
+
+// SetWebhook makes Telegram post the updates to webhookUrl along with the
+// secret, polling stops working until the webhook is deleted.
+func (b *Bot) SetWebhook(webhookUrl, secret string) error {
+	if !webhookSecretRe.MatchString(secret) {
+		return errors.New("Unable to set webhook: the secret must be 1-256 characters A-Z, a-z, 0-9, _ or -")
+	}
+
+	params := tgbotapi.Params{}
+	params.AddNonEmpty("url", webhookUrl)
+	params.AddNonEmpty("secret_token", secret)
+	if _, err := b.botApi.MakeRequest("setWebhook", params); err != nil {
+		return fmt.Errorf("Unable to set webhook: %w", err)
+	}
+	log.Printf("Webhook set to %s", webhookUrl)
+	return nil
+}
+
+// WebhookHandler passes the updates posted by Telegram to the dispatcher.
+// Requests without the secret are refused.
+func (b *Bot) WebhookHandler(secret string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get(WebhookSecretHeader)), []byte(secret)) != 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var update tgbotapi.Update
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			log.Printf("Unable to decode webhook update: %s", err.Error())
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		select {
+		case b.webhookUpdates <- update:
+		case <-r.Context().Done():
+			// Telegram sends the update again
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+}
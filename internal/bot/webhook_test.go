@@ -0,0 +1,47 @@
+package bot
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WebhookHandler(t *testing.T) {
+	b := &Bot{webhookUpdates: make(chan tgbotapi.Update, 1)}
+	handler := b.WebhookHandler("s3cret")
+
+	post := func(secret, body string) int {
+		r := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+		if secret != "" {
+			r.Header.Set(WebhookSecretHeader, secret)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, post("", `{"update_id": 1}`))
+	assert.Equal(t, http.StatusUnauthorized, post("wrong", `{"update_id": 1}`))
+	assert.Equal(t, http.StatusBadRequest, post("s3cret", `{`))
+	assert.Empty(t, b.webhookUpdates)
+
+	assert.Equal(t, http.StatusOK, post("s3cret", `{"update_id": 7, "message": {"message_id": 3, "text": "/today", "chat": {"id": 5}}}`))
+	update := <-b.webhookUpdates
+	assert.Equal(t, 7, update.UpdateID)
+	assert.Equal(t, "/today", update.Message.Text)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/webhook", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func Test_webhookSecret(t *testing.T) {
+	assert.True(t, webhookSecretRe.MatchString("abc_DEF-123"))
+	assert.False(t, webhookSecretRe.MatchString(""))
+	assert.False(t, webhookSecretRe.MatchString("has space"))
+	assert.False(t, webhookSecretRe.MatchString(strings.Repeat("a", 257)))
+}
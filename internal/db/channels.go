@@ -0,0 +1,55 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+const SelectChannelsLastPostQuery = `
+SELECT chat_id, last_post FROM channels
+`
+
+// ReadChannelsLastPost returns the time of the last post delivered to each
+// channel, the channels which haven't got any post yet are missing.
+func (s *SqliteStorage) ReadChannelsLastPost() (map[int64]time.Time, error) {
+	rows, err := s.db.Query(SelectChannelsLastPostQuery)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read channels: %w", err)
+	}
+	defer rows.Close()
+
+	lastPosts := map[int64]time.Time{}
+	for rows.Next() {
+		var chatId int64
+		var lastPostStr string
+		if err := rows.Scan(&chatId, &lastPostStr); err != nil {
+			return nil, fmt.Errorf("Unable to read channels: %w", err)
+		}
+		lastPost, err := time.Parse(time.RFC3339, lastPostStr)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read last post of channel %d: %w", chatId, err)
+		}
+		lastPosts[chatId] = lastPost
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Unable to read channels: %w", err)
+	}
+
+	return lastPosts, nil
+}
+
+const UpsertChannelLastPostQuery = `
+INSERT INTO channels(chat_id, last_post) values(?,?)
+ON CONFLICT(chat_id) DO UPDATE SET last_post = excluded.last_post
+`
+
+func (s *SqliteStorage) UpdateChannelLastPost(chatId int64, sinceTime time.Time) error {
+	sinceTimeStr := sinceTime.Format(time.RFC3339)
+	_, err := s.db.Exec(UpsertChannelLastPostQuery, chatId, sinceTimeStr)
+	if err != nil {
+		return fmt.Errorf("Unable to update last post %s for channel %d: %w", sinceTimeStr, chatId, err)
+	}
+
+	return nil
+}
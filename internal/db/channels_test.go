@@ -0,0 +1,28 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelsLastPost(t *testing.T) {
+	s := newTestStorage(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	lastPosts, err := s.ReadChannelsLastPost()
+	require.NoError(t, err)
+	assert.Empty(t, lastPosts)
+
+	require.NoError(t, s.UpdateChannelLastPost(-1001, now.Add(-time.Hour)))
+	require.NoError(t, s.UpdateChannelLastPost(-1002, now.Add(-time.Hour)))
+	require.NoError(t, s.UpdateChannelLastPost(-1001, now))
+
+	lastPosts, err = s.ReadChannelsLastPost()
+	require.NoError(t, err)
+	require.Len(t, lastPosts, 2)
+	assert.True(t, now.Equal(lastPosts[-1001]))
+	assert.True(t, now.Add(-time.Hour).Equal(lastPosts[-1002]))
+}
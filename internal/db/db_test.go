@@ -0,0 +1,23 @@
+package db
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newTestStorage opens a new database with all the tables
+func newTestStorage(t *testing.T) *SqliteStorage {
+	conn, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "db.sqlite3"))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	query, err := os.ReadFile("../../db/migrations/create_tables.sql")
+	require.NoError(t, err)
+	_, err = conn.Exec(string(query))
+	require.NoError(t, err)
+	return NewStorage(conn)
+}
@@ -0,0 +1,24 @@
+package db
+
+import (
+	"fmt"
+)
+
+const (
+	EventSubscribed   = "subscribed"
+	EventUnsubscribed = "unsubscribed"
+	// The chat is unsubscribed since messages can't be delivered to it anymore
+	EventDeactivated = "deactivated"
+)
+
+const InsertSubscriberEventQuery = `
+INSERT INTO subscriber_events(chat_id, event, reason) values(?,?,?)
+`
+
+func (s *SqliteStorage) StoreSubscriberEvent(chatId int64, event, reason string) error {
+	_, err := s.db.Exec(InsertSubscriberEventQuery, chatId, event, reason)
+	if err != nil {
+		return fmt.Errorf("Unable to store subscriber event '%s' for chat_id %d: %w", event, chatId, err)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	FeedbackClaimed       = "claimed"
+	FeedbackNotInterested = "not_interested"
+)
+
+// Feedback is the choice of a chat about a delivered post. The link and the
+// title are kept, so that claimed posts outlive the cleaned up posts.
+type Feedback struct {
+	ChatID    int64
+	PostID    int64
+	Link      string
+	Title     string
+	Status    string
+	CreatedAt time.Time
+}
+
+const UpsertFeedbackQuery = `
+INSERT INTO post_feedback(chat_id, post_id, link, title, status) values(?,?,?,?,?)
+ON CONFLICT(chat_id, post_id) DO UPDATE SET status = excluded.status, created_at = CURRENT_TIMESTAMP
+`
+
+func (s *SqliteStorage) StoreFeedback(chatId int64, post Post, status string) error {
+	_, err := s.db.Exec(UpsertFeedbackQuery, chatId, post.Id, post.Link, post.Title, status)
+	if err != nil {
+		return fmt.Errorf("Unable to store feedback for post id '%d', chat_id %d: %w", post.Id, chatId, err)
+	}
+	return nil
+}
+
+const DeleteFeedbackQuery = `
+DELETE FROM post_feedback WHERE chat_id = ? AND post_id = ?
+`
+
+func (s *SqliteStorage) DeleteFeedback(chatId, postId int64) error {
+	_, err := s.db.Exec(DeleteFeedbackQuery, chatId, postId)
+	if err != nil {
+		return fmt.Errorf("Unable to delete feedback for post id '%d', chat_id %d: %w", postId, chatId, err)
+	}
+	return nil
+}
+
+const SelectFeedbackQuery = `
+SELECT chat_id, post_id, link, title, status, created_at FROM post_feedback
+WHERE chat_id = ? AND post_id = ?
+`
+
+// GetFeedback returns an empty status if the chat gave no feedback on the post
+func (s *SqliteStorage) GetFeedback(chatId, postId int64) (Feedback, error) {
+	row := s.db.QueryRow(SelectFeedbackQuery, chatId, postId)
+	feedback, err := scanFeedback(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Feedback{ChatID: chatId, PostID: postId}, nil
+	} else if err != nil {
+		return Feedback{}, fmt.Errorf("Unable to get feedback for post id '%d', chat_id %d: %w", postId, chatId, err)
+	}
+	return feedback, nil
+}
+
+const SelectFeedbacksByStatusQuery = `
+SELECT chat_id, post_id, link, title, status, created_at FROM post_feedback
+WHERE chat_id = ? AND status = ?
+ORDER BY created_at DESC
+`
+
+func (s *SqliteStorage) ReadFeedbacks(chatId int64, status string) ([]Feedback, error) {
+	rows, err := s.db.Query(SelectFeedbacksByStatusQuery, chatId, status)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read feedback for chat_id %d: %w", chatId, err)
+	}
+	defer rows.Close()
+
+	feedbacks := []Feedback{}
+	for rows.Next() {
+		feedback, err := scanFeedback(rows)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read feedback for chat_id %d: %w", chatId, err)
+		}
+
+		feedbacks = append(feedbacks, feedback)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Unable to read feedback for chat_id %d: %w", chatId, err)
+	}
+
+	return feedbacks, nil
+}
+
+func scanFeedback(row Scanable) (Feedback, error) {
+	var feedback Feedback
+	var title sql.NullString
+
+	err := row.Scan(
+		&feedback.ChatID,
+		&feedback.PostID,
+		&feedback.Link,
+		&title,
+		&feedback.Status,
+		&feedback.CreatedAt,
+	)
+	if err != nil {
+		return Feedback{}, err
+	}
+	feedback.Title = title.String
+
+	return feedback, nil
+}
@@ -43,6 +43,19 @@ func (s *SqliteStorage) StoreBody(fetchId int64, body string) error {
 	return nil
 }
 
+const AppendFetchBodyQuery = `
+UPDATE fetch_logs SET body = COALESCE(body || char(10), '') || ? where id = ?
+`
+
+// AppendBody adds the body of one more page to the fetch, separated by a new line.
+func (s *SqliteStorage) AppendBody(fetchId int64, body string) error {
+	_, err := s.db.Exec(AppendFetchBodyQuery, body, fetchId)
+	if err != nil {
+		return fmt.Errorf("Unable to store fetch: %w", err)
+	}
+	return nil
+}
+
 const UpdateFetchErrorQuery = `
 UPDATE fetch_logs SET error = ? where id = ?
 `
@@ -85,3 +98,36 @@ func (s *SqliteStorage) DeleteFetchesOlderThan(deadline time.Time) (int64, error
 
 	return rowsDeleted, nil
 }
+
+const SelectLastFetchQuery = `
+SELECT id, created_at, COALESCE(error, '') FROM fetch_logs
+ORDER BY id DESC
+LIMIT 1
+`
+
+// GetLastFetch returns the latest fetch without its body
+func (s *SqliteStorage) GetLastFetch() (Fetch, error) {
+	var fetch Fetch
+	err := s.db.QueryRow(SelectLastFetchQuery).Scan(&fetch.Id, &fetch.CreatedAt, &fetch.Error)
+	if err != nil {
+		return Fetch{}, fmt.Errorf("Unable to get last fetch: %w", err)
+	}
+	return fetch, nil
+}
+
+const SelectLastFetchErrorQuery = `
+SELECT id, created_at, error FROM fetch_logs
+WHERE error IS NOT NULL AND error != ''
+ORDER BY id DESC
+LIMIT 1
+`
+
+// GetLastFetchError returns the latest failed fetch without its body
+func (s *SqliteStorage) GetLastFetchError() (Fetch, error) {
+	var fetch Fetch
+	err := s.db.QueryRow(SelectLastFetchErrorQuery).Scan(&fetch.Id, &fetch.CreatedAt, &fetch.Error)
+	if err != nil {
+		return Fetch{}, fmt.Errorf("Unable to get last fetch error: %w", err)
+	}
+	return fetch, nil
+}
@@ -0,0 +1,317 @@
+package db
+
+import (
+	"cmp"
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+)
+
+const (
+	OutboxPending = "pending"
+	OutboxSent    = "sent"
+	OutboxFailed  = "failed"
+)
+
+// Claimed messages are claimed again after this long, in case the replica
+// that claimed them has stopped before sending them
+const OutboxClaimTimeout = 10 * time.Minute
+
+// OutboxMessage is a message waiting to be sent to a chat. The posts of the
+// message are marked as delivered to the chat once Telegram has accepted it.
+type OutboxMessage struct {
+	Id     int64
+	ChatID int64
+	Text   string
+	// Telegram parse mode, empty for plain text
+	ParseMode             string
+	DisableWebPagePreview bool
+	// JSON of the inline keyboard, empty if there is none
+	ReplyMarkup string
+	PostIDs     []int64
+
+	Attempts      int
+	NextAttemptAt time.Time
+}
+
+const InsertOutboxMessageQuery = `
+INSERT INTO outbox(chat_id, text, parse_mode, disable_web_page_preview, reply_markup, next_attempt_at)
+values(?,?,?,?,?,?)
+`
+
+const InsertOutboxPostQuery = `
+INSERT INTO outbox_posts(outbox_id, post_id) values(?,?)
+`
+
+// EnqueueMessages stores the messages all at once, they are sent in the
+// order they are given.
+func (s *SqliteStorage) EnqueueMessages(messages []OutboxMessage, now time.Time) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("Unable to enqueue messages: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, message := range messages {
+		result, err := tx.Exec(InsertOutboxMessageQuery,
+			message.ChatID,
+			message.Text,
+			message.ParseMode,
+			message.DisableWebPagePreview,
+			nullString(message.ReplyMarkup),
+			now.UTC(),
+		)
+		if err != nil {
+			return fmt.Errorf("Unable to enqueue message for chat_id %d: %w", message.ChatID, err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("Unable to enqueue message for chat_id %d: %w", message.ChatID, err)
+		}
+
+		for _, postId := range message.PostIDs {
+			if _, err = tx.Exec(InsertOutboxPostQuery, id, postId); err != nil {
+				return fmt.Errorf("Unable to enqueue post id '%d' for chat_id %d: %w", postId, message.ChatID, err)
+			}
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("Unable to enqueue messages: %w", err)
+	}
+	return nil
+}
+
+const ClaimPendingMessagesQuery = `
+UPDATE outbox SET claimed_by = ?, claimed_at = ?
+WHERE id IN (
+	SELECT id FROM outbox
+	WHERE status = 'pending' AND next_attempt_at <= ?
+	AND (claimed_at IS NULL OR claimed_at <= ?)
+	AND NOT EXISTS (
+		SELECT 1 FROM outbox AS earlier
+		WHERE earlier.chat_id = outbox.chat_id AND earlier.status = 'pending' AND earlier.id < outbox.id
+		AND (earlier.next_attempt_at > ? OR earlier.claimed_at > ?)
+	)
+	ORDER BY id
+	LIMIT ?
+)
+RETURNING id, chat_id, text, parse_mode, disable_web_page_preview, reply_markup, attempts, next_attempt_at
+`
+
+const SelectOutboxPostsQuery = `
+SELECT post_id FROM outbox_posts WHERE outbox_id = ?
+`
+
+// ClaimPendingMessages claims the messages due at now for the claimant and
+// returns them, oldest first. Every replica of the bot drains the same
+// outbox, a claimed message isn't claimed by anyone else until it is sent,
+// retried or its claim has timed out. The messages queued after a message
+// waiting for its retry or claimed by someone else wait as well, so a chat
+// gets its messages in the order they were queued.
+func (s *SqliteStorage) ClaimPendingMessages(claimant string, now time.Time, limit int) ([]OutboxMessage, error) {
+	now = now.UTC()
+	expired := now.Add(-OutboxClaimTimeout)
+	rows, err := s.db.Query(ClaimPendingMessagesQuery, claimant, now, now, expired, now, expired, limit)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to claim pending messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []OutboxMessage{}
+	for rows.Next() {
+		var message OutboxMessage
+		var replyMarkup sql.NullString
+		err := rows.Scan(
+			&message.Id,
+			&message.ChatID,
+			&message.Text,
+			&message.ParseMode,
+			&message.DisableWebPagePreview,
+			&replyMarkup,
+			&message.Attempts,
+			&message.NextAttemptAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to claim pending messages: %w", err)
+		}
+		message.ReplyMarkup = replyMarkup.String
+
+		messages = append(messages, message)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Unable to claim pending messages: %w", err)
+	}
+	rows.Close()
+	// RETURNING gives the rows in no particular order
+	slices.SortFunc(messages, func(a, b OutboxMessage) int {
+		return cmp.Compare(a.Id, b.Id)
+	})
+
+	for i := range messages {
+		messages[i].PostIDs, err = s.readOutboxPosts(messages[i].Id)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return messages, nil
+}
+
+func (s *SqliteStorage) readOutboxPosts(outboxId int64) ([]int64, error) {
+	rows, err := s.db.Query(SelectOutboxPostsQuery, outboxId)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read posts of message '%d': %w", outboxId, err)
+	}
+	defer rows.Close()
+
+	postIds := []int64{}
+	for rows.Next() {
+		var postId int64
+		if err := rows.Scan(&postId); err != nil {
+			return nil, fmt.Errorf("Unable to read posts of message '%d': %w", outboxId, err)
+		}
+		postIds = append(postIds, postId)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Unable to read posts of message '%d': %w", outboxId, err)
+	}
+	return postIds, nil
+}
+
+const UpdateMessageSentQuery = `
+UPDATE outbox SET status = 'sent', message_id = ?, sent_at = ?, attempts = attempts + 1 WHERE id = ?
+`
+
+const InsertSentPostsQuery = `
+INSERT INTO delivered_posts(post_id, receiver)
+SELECT outbox_posts.post_id, outbox.chat_id FROM outbox_posts
+JOIN outbox ON outbox.id = outbox_posts.outbox_id
+WHERE outbox.id = ?
+`
+
+// MarkMessageSent stores the id Telegram gave to the message and marks its
+// posts as delivered
+func (s *SqliteStorage) MarkMessageSent(id int64, messageId int, sentAt time.Time) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("Unable to mark message '%d' sent: %w", id, err)
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec(UpdateMessageSentQuery, messageId, sentAt.UTC(), id); err != nil {
+		return fmt.Errorf("Unable to mark message '%d' sent: %w", id, err)
+	}
+	if _, err = tx.Exec(InsertSentPostsQuery, id); err != nil {
+		return fmt.Errorf("Unable to mark posts of message '%d' delivered: %w", id, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("Unable to mark message '%d' sent: %w", id, err)
+	}
+	return nil
+}
+
+const UpdateMessageRetryQuery = `
+UPDATE outbox SET attempts = attempts + 1, next_attempt_at = ?, last_error = ?, claimed_by = NULL, claimed_at = NULL
+WHERE id = ?
+`
+
+const ReleaseLaterMessagesQuery = `
+UPDATE outbox SET claimed_by = NULL, claimed_at = NULL
+WHERE status = 'pending' AND id > ? AND chat_id = (SELECT chat_id FROM outbox WHERE id = ?)
+`
+
+// RetryMessage puts the message off until nextAttemptAt. Its claim and the
+// claims of the later messages of the chat are released, they wait for it.
+func (s *SqliteStorage) RetryMessage(id int64, nextAttemptAt time.Time, errorStr string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("Unable to retry message '%d': %w", id, err)
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec(UpdateMessageRetryQuery, nextAttemptAt.UTC(), errorStr, id); err != nil {
+		return fmt.Errorf("Unable to retry message '%d': %w", id, err)
+	}
+	if _, err = tx.Exec(ReleaseLaterMessagesQuery, id, id); err != nil {
+		return fmt.Errorf("Unable to release messages after '%d': %w", id, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("Unable to retry message '%d': %w", id, err)
+	}
+	return nil
+}
+
+const UpdateMessageFailedQuery = `
+UPDATE outbox SET status = 'failed', attempts = attempts + 1, last_error = ? WHERE id = ?
+`
+
+// FailMessage gives up on the message, its posts are left undelivered
+func (s *SqliteStorage) FailMessage(id int64, errorStr string) error {
+	_, err := s.db.Exec(UpdateMessageFailedQuery, errorStr, id)
+	if err != nil {
+		return fmt.Errorf("Unable to fail message '%d': %w", id, err)
+	}
+	return nil
+}
+
+const SelectPostQueuedQuery = `
+SELECT EXISTS(
+	SELECT 1 FROM outbox_posts
+	JOIN outbox ON outbox.id = outbox_posts.outbox_id
+	WHERE outbox_posts.post_id = ? AND outbox.chat_id = ? AND outbox.status = 'pending'
+)
+`
+
+// IsPostQueued reports whether the post waits in the outbox to be sent to the chat
+func (s *SqliteStorage) IsPostQueued(postId, chatId int64) (bool, error) {
+	var queued bool
+	err := s.db.QueryRow(SelectPostQueuedQuery, postId, chatId).Scan(&queued)
+	if err != nil {
+		return false, fmt.Errorf("Unable to check queued post for post id '%d', chat_id %d: %w", postId, chatId, err)
+	}
+	return queued, nil
+}
+
+const DeleteOutboxPostsQuery = `
+DELETE FROM outbox_posts WHERE outbox_id IN (
+	SELECT id FROM outbox WHERE status != 'pending' AND created_at < ?
+)
+`
+
+const DeleteOutboxQuery = `
+DELETE FROM outbox WHERE status != 'pending' AND created_at < ?
+`
+
+// DeleteOutboxOlderThan deletes the sent and the failed messages, pending
+// ones are kept
+func (s *SqliteStorage) DeleteOutboxOlderThan(deadline time.Time) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("Unable to delete outbox older than %s: %w", deadline, err)
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec(DeleteOutboxPostsQuery, deadline.UTC()); err != nil {
+		return 0, fmt.Errorf("Unable to delete outbox older than %s: %w", deadline, err)
+	}
+	result, err := tx.Exec(DeleteOutboxQuery, deadline.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("Unable to delete outbox older than %s: %w", deadline, err)
+	}
+	rowsDeleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("Unable to get affected rows for outbox: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("Unable to delete outbox older than %s: %w", deadline, err)
+	}
+	return rowsDeleted, nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,129 @@
+package db
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func messageTexts(messages []OutboxMessage) []string {
+	texts := []string{}
+	for _, message := range messages {
+		texts = append(texts, message.Text)
+	}
+	return texts
+}
+
+func TestClaimPendingMessagesKeepsChatOrder(t *testing.T) {
+	s := newTestStorage(t)
+	now := time.Now().UTC()
+
+	require.NoError(t, s.EnqueueMessages([]OutboxMessage{
+		{ChatID: 1, Text: "header"},
+		{ChatID: 1, Text: "first link"},
+		{ChatID: 2, Text: "other chat"},
+		{ChatID: 1, Text: "second link"},
+	}, now))
+
+	messages, err := s.ClaimPendingMessages("a", now, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"header", "first link", "other chat", "second link"}, messageTexts(messages))
+	require.NoError(t, s.MarkMessageSent(messages[2].Id, 100, now))
+
+	// The header fails, the links of the chat wait for it
+	require.NoError(t, s.RetryMessage(messages[0].Id, now.Add(time.Minute), "timeout"))
+	require.NoError(t, s.EnqueueMessages([]OutboxMessage{{ChatID: 1, Text: "queued later"}}, now.Add(time.Second)))
+
+	messages, err = s.ClaimPendingMessages("a", now.Add(2*time.Second), 10)
+	require.NoError(t, err)
+	assert.Empty(t, messages)
+
+	messages, err = s.ClaimPendingMessages("a", now.Add(time.Minute), 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"header", "first link", "second link", "queued later"}, messageTexts(messages))
+	assert.Equal(t, 1, messages[0].Attempts)
+
+	// A failed message doesn't hold up the rest once their claim times out
+	require.NoError(t, s.FailMessage(messages[0].Id, "message is too long"))
+	messages, err = s.ClaimPendingMessages("a", now.Add(time.Minute+OutboxClaimTimeout), 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first link", "second link", "queued later"}, messageTexts(messages))
+}
+
+func TestClaimPendingMessagesOnce(t *testing.T) {
+	s := newTestStorage(t)
+	now := time.Now().UTC()
+
+	require.NoError(t, s.EnqueueMessages([]OutboxMessage{
+		{ChatID: 1, Text: "header"},
+		{ChatID: 2, Text: "other chat"},
+	}, now))
+	messages, err := s.ClaimPendingMessages("a", now, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"header"}, messageTexts(messages))
+
+	// Another replica gets the rest only
+	require.NoError(t, s.EnqueueMessages([]OutboxMessage{{ChatID: 1, Text: "link"}}, now))
+	messages, err = s.ClaimPendingMessages("b", now, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"other chat"}, messageTexts(messages))
+
+	messages, err = s.ClaimPendingMessages("b", now.Add(time.Second), 10)
+	require.NoError(t, err)
+	assert.Empty(t, messages)
+
+	// The first replica has stopped before sending
+	messages, err = s.ClaimPendingMessages("b", now.Add(OutboxClaimTimeout), 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"header", "other chat", "link"}, messageTexts(messages))
+}
+
+func TestMarkMessageSentDeliversPosts(t *testing.T) {
+	s := newTestStorage(t)
+	now := time.Now().UTC()
+
+	require.NoError(t, s.EnqueueMessages([]OutboxMessage{
+		{ChatID: 1, Text: "header"},
+		{ChatID: 1, Text: "digest", PostIDs: []int64{10, 11}},
+		{ChatID: 2, Text: "digest", PostIDs: []int64{10}},
+	}, now))
+	messages, err := s.ClaimPendingMessages("a", now, 10)
+	require.NoError(t, err)
+	require.Len(t, messages, 3)
+	assert.Equal(t, []int64{10, 11}, messages[1].PostIDs)
+
+	delivered := func(postId, chatId int64) bool {
+		_, err := s.GetDeliveredPost(postId, chatId)
+		if err != nil {
+			require.ErrorIs(t, err, sql.ErrNoRows)
+			return false
+		}
+		return true
+	}
+	queued := func(postId, chatId int64) bool {
+		queued, err := s.IsPostQueued(postId, chatId)
+		require.NoError(t, err)
+		return queued
+	}
+
+	// Neither a retry nor a failure delivers the posts
+	require.NoError(t, s.RetryMessage(messages[1].Id, now, "timeout"))
+	require.NoError(t, s.FailMessage(messages[2].Id, "Forbidden: bot was blocked by the user"))
+	assert.False(t, delivered(10, 1))
+	assert.True(t, queued(10, 1))
+	assert.False(t, delivered(10, 2))
+	assert.False(t, queued(10, 2))
+
+	require.NoError(t, s.MarkMessageSent(messages[1].Id, 42, now))
+	assert.True(t, delivered(10, 1))
+	assert.True(t, delivered(11, 1))
+	assert.False(t, queued(10, 1))
+	assert.False(t, delivered(10, 2))
+
+	messages, err = s.ClaimPendingMessages("a", now.Add(OutboxClaimTimeout), 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"header"}, messageTexts(messages))
+}
@@ -1,7 +1,9 @@
 package db
 
 import (
+	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -11,46 +13,276 @@ type Post struct {
 	Link      string
 	Title     string
 	PostedAt  time.Time
+	ExpiresAt time.Time
 	CreatedAt time.Time
+
+	// Listing metadata, empty for the sources without it
+	Flair     string
+	Domain    string
+	Author    string
+	Score     int
+	Comments  int
+	Permalink string
+
+	Platform string
+	DealType string
+	// Name of the source that found the post first, empty for older posts
+	Source string
 }
 
 const InsertPostQuery = `
-INSERT INTO posts(fetch_id, link, title, posted_at) values(?,?,?,?)
+INSERT INTO posts(fetch_id, link, title, posted_at, expires_at, flair, domain, author, score, comments, permalink, platform, deal_type)
+values(?,?,?,?,?,?,?,?,?,?,?,?,?)
 ON CONFLICT(link) DO NOTHING
 `
 
-func (s *SqliteStorage) StorePost(fetch_id int64, link, title string, postedAt time.Time) error {
-	_, err := s.db.Exec(InsertPostQuery, fetch_id, link, title, postedAt)
+// StorePost stores a newly found post. Id and CreatedAt are ignored,
+// zero ExpiresAt is stored as unknown.
+func (s *SqliteStorage) StorePost(post Post) error {
+	_, err := s.db.Exec(InsertPostQuery,
+		post.FetchId,
+		post.Link,
+		post.Title,
+		post.PostedAt,
+		nullTime(post.ExpiresAt),
+		post.Flair,
+		post.Domain,
+		post.Author,
+		post.Score,
+		post.Comments,
+		post.Permalink,
+		post.Platform,
+		post.DealType,
+	)
+	if err != nil {
+		return fmt.Errorf("Unable to store post for fetch id '%d', link '%s': %w", post.FetchId, post.Link, err)
+	}
+	return nil
+}
+
+const UpsertPromotionQuery = `
+INSERT INTO posts(fetch_id, link, title, posted_at, expires_at, flair, domain, author, score, comments, permalink, platform, deal_type)
+values(?,?,?,?,?,?,?,?,?,?,?,?,?)
+ON CONFLICT(link) DO UPDATE SET posted_at = excluded.posted_at, expires_at = excluded.expires_at
+WHERE excluded.posted_at > posts.posted_at
+`
+
+// StorePromotion stores the post of a store promotion like StorePost. The
+// store gives the same link away again later, a newer promotion moves the
+// stored post to its dates.
+func (s *SqliteStorage) StorePromotion(post Post) error {
+	_, err := s.db.Exec(UpsertPromotionQuery,
+		post.FetchId,
+		post.Link,
+		post.Title,
+		post.PostedAt,
+		nullTime(post.ExpiresAt),
+		post.Flair,
+		post.Domain,
+		post.Author,
+		post.Score,
+		post.Comments,
+		post.Permalink,
+		post.Platform,
+		post.DealType,
+	)
 	if err != nil {
-		return fmt.Errorf("Unable to store post for fetch id '%d', link '%s': %w", fetch_id, link, err)
+		return fmt.Errorf("Unable to store promotion for fetch id '%d', link '%s': %w", post.FetchId, post.Link, err)
 	}
 	return nil
 }
 
+const UpdatePostsSourceQuery = `
+UPDATE posts SET source = ? WHERE fetch_id = ?
+`
+
+// UpdatePostsSource names the source of the posts stored by the fetch
+func (s *SqliteStorage) UpdatePostsSource(fetchId int64, source string) error {
+	_, err := s.db.Exec(UpdatePostsSourceQuery, source, fetchId)
+	if err != nil {
+		return fmt.Errorf("Unable to update source of posts for fetch id '%d': %w", fetchId, err)
+	}
+	return nil
+}
+
+const SelectFetchPostsQuery = `
+SELECT ` + postColumns + ` FROM posts
+WHERE fetch_id = ?
+`
+
+// ReadFetchPosts returns the posts first stored by the fetch
+func (s *SqliteStorage) ReadFetchPosts(fetchId int64) ([]Post, error) {
+	rows, err := s.db.Query(SelectFetchPostsQuery, fetchId)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read posts of fetch id '%d': %w", fetchId, err)
+	}
+	defer rows.Close()
+
+	posts := []Post{}
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read posts of fetch id '%d': %w", fetchId, err)
+		}
+
+		posts = append(posts, post)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Unable to read posts of fetch id '%d': %w", fetchId, err)
+	}
+
+	return posts, nil
+}
+
 const SelectPostByLinkQuery = `
-SELECT id, fetch_id, link, title, posted_at, created_at FROM posts
+SELECT ` + postColumns + ` FROM posts
 WHERE link = ?
 `
 
 func (s *SqliteStorage) GetPostByLink(link string) (Post, error) {
 	row := s.db.QueryRow(SelectPostByLinkQuery, link)
 
+	post, err := scanPost(row)
+	if err != nil {
+		return Post{}, fmt.Errorf("Unable to scan post for link '%s': %w", link, err)
+	}
+
+	return post, nil
+}
+
+const SelectPostByIdQuery = `
+SELECT ` + postColumns + ` FROM posts
+WHERE id = ?
+`
+
+func (s *SqliteStorage) GetPost(id int64) (Post, error) {
+	row := s.db.QueryRow(SelectPostByIdQuery, id)
+
+	post, err := scanPost(row)
+	if err != nil {
+		return Post{}, fmt.Errorf("Unable to scan post for id '%d': %w", id, err)
+	}
+
+	return post, nil
+}
+
+const postColumns = `id, fetch_id, link, title, posted_at, expires_at, created_at,
+flair, domain, author, score, comments, permalink, platform, deal_type, source`
+
+const SelectPostsSinceQuery = `
+SELECT ` + postColumns + ` FROM posts
+WHERE posted_at > ?
+ORDER BY posted_at DESC
+`
+
+func (s *SqliteStorage) ReadPostsSince(sinceTime time.Time) ([]Post, error) {
+	rows, err := s.db.Query(SelectPostsSinceQuery, sinceTime.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read posts since %s: %w", sinceTime, err)
+	}
+	defer rows.Close()
+
+	posts := []Post{}
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read posts since %s: %w", sinceTime, err)
+		}
+
+		posts = append(posts, post)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Unable to read posts since %s: %w", sinceTime, err)
+	}
+
+	return posts, nil
+}
+
+const SearchPostsQuery = `
+SELECT ` + postColumns + ` FROM posts
+WHERE posted_at > ? AND (expires_at IS NULL OR expires_at > ?)
+AND (title LIKE ? ESCAPE '\' OR link LIKE ? ESCAPE '\')
+ORDER BY posted_at DESC
+LIMIT ?
+`
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// SearchPosts returns the posts posted since sinceTime and not expired at now
+// having the text in their title or link, newest first.
+func (s *SqliteStorage) SearchPosts(text string, sinceTime, now time.Time, limit int) ([]Post, error) {
+	pattern := "%" + likeEscaper.Replace(text) + "%"
+	rows, err := s.db.Query(SearchPostsQuery, sinceTime.UTC(), now.UTC(), pattern, pattern, limit)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to search posts for '%s': %w", text, err)
+	}
+	defer rows.Close()
+
+	posts := []Post{}
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to search posts for '%s': %w", text, err)
+		}
+
+		posts = append(posts, post)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Unable to search posts for '%s': %w", text, err)
+	}
+
+	return posts, nil
+}
+
+func scanPost(row Scanable) (Post, error) {
 	var post Post
+	var title, flair, domain, author, permalink, platform, dealType, source sql.NullString
+	var score, comments sql.NullInt64
+	var expiresAt sql.NullTime
+
 	err := row.Scan(
 		&post.Id,
 		&post.FetchId,
 		&post.Link,
-		&post.Title,
+		&title,
 		&post.PostedAt,
+		&expiresAt,
 		&post.CreatedAt,
+		&flair,
+		&domain,
+		&author,
+		&score,
+		&comments,
+		&permalink,
+		&platform,
+		&dealType,
+		&source,
 	)
 	if err != nil {
-		return Post{}, fmt.Errorf("Unable to scan post for link '%s': %w", link, err)
+		return Post{}, err
 	}
+	post.Title = title.String
+	post.ExpiresAt = expiresAt.Time
+	post.Flair = flair.String
+	post.Domain = domain.String
+	post.Author = author.String
+	post.Score = int(score.Int64)
+	post.Comments = int(comments.Int64)
+	post.Permalink = permalink.String
+	post.Platform = platform.String
+	post.DealType = dealType.String
+	post.Source = source.String
 
 	return post, nil
 }
 
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
 const DeletePostsQuery = `
 DELETE FROM posts WHERE created_at < ?
 `
@@ -125,3 +357,50 @@ func (s *SqliteStorage) DeleteDeliveredPostsOlderThan(deadline time.Time) (int64
 
 	return rowsDeleted, nil
 }
+
+const SelectPostsHistoryQuery = `
+SELECT ` + postColumns + ` FROM posts
+WHERE id IN (SELECT rowid FROM posts_fts WHERE posts_fts MATCH ?)
+ORDER BY posted_at DESC, id DESC
+`
+
+// SearchPostsHistory looks for the words of text in the titles and the links
+// of every stored post, newest first. The bot filters the posts before
+// splitting them into pages, so all of them are returned.
+func (s *SqliteStorage) SearchPostsHistory(text string) ([]Post, error) {
+	match := ftsQuery(text)
+	if match == "" {
+		return []Post{}, nil
+	}
+
+	rows, err := s.db.Query(SelectPostsHistoryQuery, match)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to search posts for '%s': %w", text, err)
+	}
+	defer rows.Close()
+
+	posts := []Post{}
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to search posts for '%s': %w", text, err)
+		}
+		posts = append(posts, post)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Unable to search posts for '%s': %w", text, err)
+	}
+
+	return posts, nil
+}
+
+// ftsQuery makes every word of text a prefix search, quoted so the FTS5
+// syntax in the words is searched literally
+func ftsQuery(text string) string {
+	terms := []string{}
+	for _, word := range strings.Fields(text) {
+		terms = append(terms, `"`+strings.ReplaceAll(word, `"`, `""`)+`"*`)
+	}
+	return strings.Join(terms, " ")
+}
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdatePostsSource(t *testing.T) {
+	s := newTestStorage(t)
+	now := time.Now().UTC()
+
+	require.NoError(t, s.StorePost(Post{FetchId: 1, Link: "https://store.steampowered.com/app/1/", PostedAt: now}))
+	require.NoError(t, s.StorePost(Post{FetchId: 2, Link: "https://store.epicgames.com/p/hades", PostedAt: now}))
+	require.NoError(t, s.UpdatePostsSource(1, "freegamefindings"))
+
+	posts, err := s.ReadPostsSince(now.Add(-time.Hour))
+	require.NoError(t, err)
+	sources := map[string]string{}
+	for _, post := range posts {
+		sources[post.Link] = post.Source
+	}
+	assert.Equal(t, map[string]string{
+		"https://store.steampowered.com/app/1/": "freegamefindings",
+		"https://store.epicgames.com/p/hades":   "",
+	}, sources)
+	posts, err = s.ReadFetchPosts(2)
+	require.NoError(t, err)
+	require.Len(t, posts, 1)
+	assert.Equal(t, "https://store.epicgames.com/p/hades", posts[0].Link)
+}
+
+func TestStorePromotion(t *testing.T) {
+	s := newTestStorage(t)
+	start := time.Date(2026, 10, 15, 15, 0, 0, 0, time.UTC)
+	link := "https://store.epicgames.com/en-US/p/hogwarts-legacy"
+
+	require.NoError(t, s.StorePromotion(Post{FetchId: 1, Link: link, PostedAt: start, ExpiresAt: start.AddDate(0, 0, 7)}))
+	first, err := s.GetPostByLink(link)
+	require.NoError(t, err)
+
+	// Fetched again during the same promotion
+	require.NoError(t, s.StorePromotion(Post{FetchId: 2, Link: link, PostedAt: start, ExpiresAt: start.AddDate(0, 0, 7)}))
+	post, err := s.GetPostByLink(link)
+	require.NoError(t, err)
+	assert.Equal(t, first, post)
+
+	// Given away again
+	require.NoError(t, s.StorePromotion(Post{FetchId: 3, Link: link, PostedAt: start.AddDate(0, 6, 0), ExpiresAt: start.AddDate(0, 6, 7)}))
+	post, err = s.GetPostByLink(link)
+	require.NoError(t, err)
+	assert.Equal(t, first.Id, post.Id)
+	assert.Equal(t, start.AddDate(0, 6, 0), post.PostedAt.UTC())
+	assert.Equal(t, start.AddDate(0, 6, 7), post.ExpiresAt.UTC())
+}
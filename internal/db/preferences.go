@@ -0,0 +1,298 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	DeliveryInstant = "instant"
+	DeliveryDaily   = "daily"
+	DeliveryWeekly  = "weekly"
+)
+
+// Preferences are the delivery settings of a chat. Chats without
+// stored preferences get the defaults, i.e. every freebie.
+type Preferences struct {
+	ChatID          int64
+	HiddenPlatforms []string
+	// Only the links matching Keywords are delivered, instead of muting them
+	AllowOnly bool
+	Keywords  []string
+	// Links are sent as a single digest message
+	BatchLinks bool
+
+	// New freebies are pushed as soon as found in instant mode, otherwise
+	// they are sent as a digest at DigestTime minutes after midnight
+	DeliveryMode  string
+	DigestTime    int
+	DigestWeekday time.Weekday
+	LastDigestAt  time.Time
+
+	// IANA name of the timezone the chat's days and times are in
+	Timezone string
+	// Pushes are held between QuietStart and QuietEnd minutes after
+	// the local midnight, equal values turn quiet hours off
+	QuietStart int
+	QuietEnd   int
+
+	// Language of the replies, empty until known
+	Language string
+}
+
+func DefaultPreferences(chatId int64) Preferences {
+	return Preferences{
+		ChatID:          chatId,
+		HiddenPlatforms: []string{},
+		DeliveryMode:    DeliveryInstant,
+		DigestTime:      9 * 60,
+		DigestWeekday:   time.Monday,
+		Timezone:        "UTC",
+	}
+}
+
+const SelectPreferencesQuery = `
+SELECT chat_id, hidden_platforms, allow_only, batch_links,
+delivery_mode, digest_time, digest_weekday, last_digest_at,
+timezone, quiet_start, quiet_end, language FROM subscriber_preferences
+WHERE chat_id = ?
+`
+
+func (s *SqliteStorage) GetPreferences(chatId int64) (Preferences, error) {
+	row := s.db.QueryRow(SelectPreferencesQuery, chatId)
+	preferences, err := scanPreferences(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		preferences = DefaultPreferences(chatId)
+	} else if err != nil {
+		return Preferences{}, fmt.Errorf("Unable to get preferences for chat_id %d: %w", chatId, err)
+	}
+
+	preferences.Keywords, err = s.ReadKeywords(chatId)
+	if err != nil {
+		return Preferences{}, fmt.Errorf("Unable to get preferences for chat_id %d: %w", chatId, err)
+	}
+
+	return preferences, nil
+}
+
+const UpsertHiddenPlatformsQuery = `
+INSERT INTO subscriber_preferences(chat_id, hidden_platforms) values(?,?)
+ON CONFLICT(chat_id) DO UPDATE SET hidden_platforms = excluded.hidden_platforms
+`
+
+func (s *SqliteStorage) UpdateHiddenPlatforms(chatId int64, platforms []string) error {
+	_, err := s.db.Exec(UpsertHiddenPlatformsQuery, chatId, joinList(platforms))
+	if err != nil {
+		return fmt.Errorf("Unable to update hidden platforms for chat_id %d: %w", chatId, err)
+	}
+
+	return nil
+}
+
+const UpsertAllowOnlyQuery = `
+INSERT INTO subscriber_preferences(chat_id, allow_only) values(?,?)
+ON CONFLICT(chat_id) DO UPDATE SET allow_only = excluded.allow_only
+`
+
+func (s *SqliteStorage) UpdateAllowOnly(chatId int64, allowOnly bool) error {
+	_, err := s.db.Exec(UpsertAllowOnlyQuery, chatId, allowOnly)
+	if err != nil {
+		return fmt.Errorf("Unable to update allow only for chat_id %d: %w", chatId, err)
+	}
+
+	return nil
+}
+
+const UpsertBatchLinksQuery = `
+INSERT INTO subscriber_preferences(chat_id, batch_links) values(?,?)
+ON CONFLICT(chat_id) DO UPDATE SET batch_links = excluded.batch_links
+`
+
+func (s *SqliteStorage) UpdateBatchLinks(chatId int64, batchLinks bool) error {
+	_, err := s.db.Exec(UpsertBatchLinksQuery, chatId, batchLinks)
+	if err != nil {
+		return fmt.Errorf("Unable to update batch links for chat_id %d: %w", chatId, err)
+	}
+
+	return nil
+}
+
+const UpsertDigestQuery = `
+INSERT INTO subscriber_preferences(chat_id, delivery_mode, digest_time, digest_weekday, last_digest_at) values(?,?,?,?,?)
+ON CONFLICT(chat_id) DO UPDATE SET
+delivery_mode = excluded.delivery_mode,
+digest_time = excluded.digest_time,
+digest_weekday = excluded.digest_weekday,
+last_digest_at = excluded.last_digest_at
+`
+
+// UpdateDigest sets the delivery mode, the first digest is sent at the
+// scheduled time following now.
+func (s *SqliteStorage) UpdateDigest(chatId int64, mode string, digestTime int, weekday time.Weekday, now time.Time) error {
+	_, err := s.db.Exec(UpsertDigestQuery, chatId, mode, digestTime, int(weekday), now.UTC())
+	if err != nil {
+		return fmt.Errorf("Unable to update digest for chat_id %d: %w", chatId, err)
+	}
+
+	return nil
+}
+
+const UpdateLastDigestQuery = `
+UPDATE subscriber_preferences SET last_digest_at = ? WHERE chat_id = ?
+`
+
+func (s *SqliteStorage) UpdateLastDigest(chatId int64, sentAt time.Time) error {
+	_, err := s.db.Exec(UpdateLastDigestQuery, sentAt.UTC(), chatId)
+	if err != nil {
+		return fmt.Errorf("Unable to update last digest for chat_id %d: %w", chatId, err)
+	}
+
+	return nil
+}
+
+const UpsertTimezoneQuery = `
+INSERT INTO subscriber_preferences(chat_id, timezone) values(?,?)
+ON CONFLICT(chat_id) DO UPDATE SET timezone = excluded.timezone
+`
+
+func (s *SqliteStorage) UpdateTimezone(chatId int64, timezone string) error {
+	_, err := s.db.Exec(UpsertTimezoneQuery, chatId, timezone)
+	if err != nil {
+		return fmt.Errorf("Unable to update timezone for chat_id %d: %w", chatId, err)
+	}
+
+	return nil
+}
+
+const UpsertQuietHoursQuery = `
+INSERT INTO subscriber_preferences(chat_id, quiet_start, quiet_end) values(?,?,?)
+ON CONFLICT(chat_id) DO UPDATE SET quiet_start = excluded.quiet_start, quiet_end = excluded.quiet_end
+`
+
+func (s *SqliteStorage) UpdateQuietHours(chatId int64, start, end int) error {
+	_, err := s.db.Exec(UpsertQuietHoursQuery, chatId, start, end)
+	if err != nil {
+		return fmt.Errorf("Unable to update quiet hours for chat_id %d: %w", chatId, err)
+	}
+
+	return nil
+}
+
+const UpsertLanguageQuery = `
+INSERT INTO subscriber_preferences(chat_id, language) values(?,?)
+ON CONFLICT(chat_id) DO UPDATE SET language = excluded.language
+`
+
+func (s *SqliteStorage) UpdateLanguage(chatId int64, language string) error {
+	_, err := s.db.Exec(UpsertLanguageQuery, chatId, language)
+	if err != nil {
+		return fmt.Errorf("Unable to update language for chat_id %d: %w", chatId, err)
+	}
+
+	return nil
+}
+
+const InsertKeywordQuery = `
+INSERT OR IGNORE INTO subscriber_keywords(chat_id, word) values(?,?)
+`
+
+func (s *SqliteStorage) StoreKeyword(chatId int64, word string) error {
+	_, err := s.db.Exec(InsertKeywordQuery, chatId, word)
+	if err != nil {
+		return fmt.Errorf("Unable to store keyword '%s' for chat_id %d: %w", word, chatId, err)
+	}
+
+	return nil
+}
+
+const DeleteKeywordQuery = `
+DELETE FROM subscriber_keywords WHERE chat_id = ? AND word = ?
+`
+
+// DeleteKeyword returns false if the chat has no such keyword
+func (s *SqliteStorage) DeleteKeyword(chatId int64, word string) (bool, error) {
+	result, err := s.db.Exec(DeleteKeywordQuery, chatId, word)
+	if err != nil {
+		return false, fmt.Errorf("Unable to delete keyword '%s' for chat_id %d: %w", word, chatId, err)
+	}
+
+	rowsDeleted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("Unable to get affected rows for keywords: %w", err)
+	}
+
+	return rowsDeleted > 0, nil
+}
+
+const SelectKeywordsQuery = `
+SELECT word FROM subscriber_keywords
+WHERE chat_id = ?
+ORDER BY word
+`
+
+func (s *SqliteStorage) ReadKeywords(chatId int64) ([]string, error) {
+	rows, err := s.db.Query(SelectKeywordsQuery, chatId)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read keywords: %w", err)
+	}
+	defer rows.Close()
+
+	keywords := []string{}
+	for rows.Next() {
+		var word string
+		if err := rows.Scan(&word); err != nil {
+			return nil, fmt.Errorf("Unable to read keywords: %w", err)
+		}
+
+		keywords = append(keywords, word)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Unable to read keywords: %w", err)
+	}
+
+	return keywords, nil
+}
+
+func scanPreferences(row Scanable) (Preferences, error) {
+	var preferences Preferences
+	var hiddenPlatforms string
+	var lastDigestAt sql.NullTime
+
+	err := row.Scan(
+		&preferences.ChatID,
+		&hiddenPlatforms,
+		&preferences.AllowOnly,
+		&preferences.BatchLinks,
+		&preferences.DeliveryMode,
+		&preferences.DigestTime,
+		&preferences.DigestWeekday,
+		&lastDigestAt,
+		&preferences.Timezone,
+		&preferences.QuietStart,
+		&preferences.QuietEnd,
+		&preferences.Language,
+	)
+	if err != nil {
+		return Preferences{}, err
+	}
+	preferences.HiddenPlatforms = splitList(hiddenPlatforms)
+	preferences.LastDigestAt = lastDigestAt.Time
+
+	return preferences, nil
+}
+
+// Lists are stored as comma separated values
+func joinList(values []string) string {
+	return strings.Join(values, ",")
+}
+
+func splitList(value string) []string {
+	if value == "" {
+		return []string{}
+	}
+	return strings.Split(value, ",")
+}
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"fmt"
+)
+
+const SelectMutedSourcesQuery = `
+SELECT name FROM muted_sources ORDER BY name
+`
+
+// ReadMutedSources returns the names of the sources that aren't fetched
+func (s *SqliteStorage) ReadMutedSources() ([]string, error) {
+	rows, err := s.db.Query(SelectMutedSourcesQuery)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read muted sources: %w", err)
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("Unable to read muted sources: %w", err)
+		}
+		names = append(names, name)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Unable to read muted sources: %w", err)
+	}
+
+	return names, nil
+}
+
+const InsertMutedSourceQuery = `
+INSERT OR IGNORE INTO muted_sources(name) values(?)
+`
+
+func (s *SqliteStorage) MuteSource(name string) error {
+	_, err := s.db.Exec(InsertMutedSourceQuery, name)
+	if err != nil {
+		return fmt.Errorf("Unable to mute source '%s': %w", name, err)
+	}
+	return nil
+}
+
+const DeleteMutedSourceQuery = `
+DELETE FROM muted_sources WHERE name = ?
+`
+
+func (s *SqliteStorage) UnmuteSource(name string) error {
+	_, err := s.db.Exec(DeleteMutedSourceQuery, name)
+	if err != nil {
+		return fmt.Errorf("Unable to unmute source '%s': %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// Stats tell how the bot is doing since a moment
+type Stats struct {
+	Subscribers     int
+	PostsFound      int
+	Fetches         int
+	FailedFetches   int
+	MessagesSent    int
+	MessagesFailed  int
+	MessagesPending int
+}
+
+const SelectStatsQuery = `
+SELECT
+(SELECT COUNT(*) FROM subscribers),
+(SELECT COUNT(*) FROM posts WHERE created_at >= ?),
+(SELECT COUNT(*) FROM fetch_logs WHERE created_at >= ?),
+(SELECT COUNT(*) FROM fetch_logs WHERE created_at >= ? AND error IS NOT NULL),
+(SELECT COUNT(*) FROM outbox WHERE status = 'sent' AND sent_at >= ?),
+(SELECT COUNT(*) FROM outbox WHERE status = 'failed' AND created_at >= ?),
+(SELECT COUNT(*) FROM outbox WHERE status = 'pending')
+`
+
+func (s *SqliteStorage) ReadStats(sinceTime time.Time) (Stats, error) {
+	since := sinceTime.UTC()
+	var stats Stats
+	err := s.db.QueryRow(SelectStatsQuery, since, since, since, since, since).Scan(
+		&stats.Subscribers,
+		&stats.PostsFound,
+		&stats.Fetches,
+		&stats.FailedFetches,
+		&stats.MessagesSent,
+		&stats.MessagesFailed,
+		&stats.MessagesPending,
+	)
+	if err != nil {
+		return Stats{}, fmt.Errorf("Unable to read stats since %s: %w", sinceTime, err)
+	}
+	return stats, nil
+}
+
+// SubscriberStats count the subscribers and the changes since a moment
+type SubscriberStats struct {
+	Total   int
+	Groups  int
+	Digests int
+	// Events by their kind, e.g. EventSubscribed
+	Events map[string]int
+}
+
+const SelectSubscriberCountsQuery = `
+SELECT
+COUNT(*),
+COUNT(CASE WHEN subscribers.chat_id < 0 THEN 1 END),
+COUNT(CASE WHEN subscriber_preferences.delivery_mode != 'instant' THEN 1 END)
+FROM subscribers
+LEFT JOIN subscriber_preferences ON subscriber_preferences.chat_id = subscribers.chat_id
+`
+
+const SelectSubscriberEventCountsQuery = `
+SELECT event, COUNT(*) FROM subscriber_events
+WHERE created_at >= ?
+GROUP BY event
+`
+
+func (s *SqliteStorage) ReadSubscriberStats(sinceTime time.Time) (SubscriberStats, error) {
+	stats := SubscriberStats{Events: map[string]int{}}
+	err := s.db.QueryRow(SelectSubscriberCountsQuery).Scan(&stats.Total, &stats.Groups, &stats.Digests)
+	if err != nil {
+		return SubscriberStats{}, fmt.Errorf("Unable to read subscriber stats: %w", err)
+	}
+
+	rows, err := s.db.Query(SelectSubscriberEventCountsQuery, sinceTime.UTC())
+	if err != nil {
+		return SubscriberStats{}, fmt.Errorf("Unable to read subscriber events since %s: %w", sinceTime, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var event string
+		var count int
+		if err := rows.Scan(&event, &count); err != nil {
+			return SubscriberStats{}, fmt.Errorf("Unable to read subscriber events since %s: %w", sinceTime, err)
+		}
+		stats.Events[event] = count
+	}
+	if err = rows.Err(); err != nil {
+		return SubscriberStats{}, fmt.Errorf("Unable to read subscriber events since %s: %w", sinceTime, err)
+	}
+
+	return stats, nil
+}
@@ -118,3 +118,33 @@ func scanSubscriber(row Scanable) (Subscriber, error) {
 	}
 	return subscriber, nil
 }
+
+var migrateChatQueries = []string{
+	`UPDATE subscribers SET chat_id = ? WHERE chat_id = ?`,
+	`UPDATE OR REPLACE subscriber_preferences SET chat_id = ? WHERE chat_id = ?`,
+	`UPDATE OR IGNORE subscriber_keywords SET chat_id = ? WHERE chat_id = ?`,
+	`UPDATE delivered_posts SET receiver = ? WHERE receiver = ?`,
+	`UPDATE OR REPLACE post_feedback SET chat_id = ? WHERE chat_id = ?`,
+	`UPDATE outbox SET chat_id = ? WHERE chat_id = ?`,
+}
+
+// MigrateChat moves the subscription and the settings of a group
+// upgraded to a supergroup to its new chat id.
+func (s *SqliteStorage) MigrateChat(fromChatId, toChatId int64) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("Unable to migrate chat_id %d to %d: %w", fromChatId, toChatId, err)
+	}
+	defer tx.Rollback()
+
+	for _, query := range migrateChatQueries {
+		if _, err = tx.Exec(query, toChatId, fromChatId); err != nil {
+			return fmt.Errorf("Unable to migrate chat_id %d to %d: %w", fromChatId, toChatId, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("Unable to migrate chat_id %d to %d: %w", fromChatId, toChatId, err)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package db
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateChat(t *testing.T) {
+	s := newTestStorage(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	require.NoError(t, s.StoreSubscriber(-1, now))
+	require.NoError(t, s.UpdateBatchLinks(-1, true))
+	require.NoError(t, s.StoreKeyword(-1, "dlc"))
+	require.NoError(t, s.StorePost(Post{FetchId: 1, Link: "https://store.steampowered.com/app/1/", PostedAt: now}))
+	post, err := s.GetPostByLink("https://store.steampowered.com/app/1/")
+	require.NoError(t, err)
+	require.NoError(t, s.StoreDeliveredPost(post.Id, -1))
+	require.NoError(t, s.StoreFeedback(-1, post, FeedbackClaimed))
+	// Left by an earlier message of the supergroup, replaced by the settings of the group
+	require.NoError(t, s.UpdateLanguage(-100, "ru"))
+
+	require.NoError(t, s.MigrateChat(-1, -100))
+
+	_, err = s.GetSubscriber(-1)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	subscriber, err := s.GetSubscriber(-100)
+	require.NoError(t, err)
+	assert.Equal(t, now, subscriber.LastPost.UTC())
+
+	preferences, err := s.GetPreferences(-100)
+	require.NoError(t, err)
+	assert.True(t, preferences.BatchLinks)
+	assert.Equal(t, "", preferences.Language)
+	assert.Equal(t, []string{"dlc"}, preferences.Keywords)
+
+	_, err = s.GetDeliveredPost(post.Id, -100)
+	assert.NoError(t, err)
+	feedback, err := s.GetFeedback(-100, post.Id)
+	require.NoError(t, err)
+	assert.Equal(t, FeedbackClaimed, feedback.Status)
+}
+
+func TestMigrateChatRollsBack(t *testing.T) {
+	s := newTestStorage(t)
+	require.NoError(t, s.StoreSubscriber(-1, time.Now()))
+	require.NoError(t, s.UpdateBatchLinks(-1, true))
+	// The last query fails
+	_, err := s.db.Exec("DROP TABLE outbox")
+	require.NoError(t, err)
+
+	assert.Error(t, s.MigrateChat(-1, -100))
+
+	_, err = s.GetSubscriber(-1)
+	assert.NoError(t, err)
+	_, err = s.GetSubscriber(-100)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	preferences, err := s.GetPreferences(-1)
+	require.NoError(t, err)
+	assert.True(t, preferences.BatchLinks)
+}
@@ -0,0 +1,227 @@
+package fetchers
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	PlatformSteam       = "steam"
+	PlatformEpic        = "epic"
+	PlatformGOG         = "gog"
+	PlatformItch        = "itch"
+	PlatformPrime       = "prime"
+	PlatformUbisoft     = "ubisoft"
+	PlatformEA          = "ea"
+	PlatformXbox        = "xbox"
+	PlatformPlayStation = "playstation"
+	PlatformIndieGala   = "indiegala"
+	PlatformAndroid     = "android"
+	PlatformIOS         = "ios"
+	PlatformOther       = "other"
+)
+
+// Platforms lists every platform in the order it is shown to users
+var Platforms = []string{
+	PlatformSteam,
+	PlatformEpic,
+	PlatformGOG,
+	PlatformItch,
+	PlatformPrime,
+	PlatformUbisoft,
+	PlatformEA,
+	PlatformXbox,
+	PlatformPlayStation,
+	PlatformIndieGala,
+	PlatformAndroid,
+	PlatformIOS,
+	PlatformOther,
+}
+
+var PlatformNames = map[string]string{
+	PlatformSteam:       "Steam",
+	PlatformEpic:        "Epic Games",
+	PlatformGOG:         "GOG",
+	PlatformItch:        "itch.io",
+	PlatformPrime:       "Prime Gaming",
+	PlatformUbisoft:     "Ubisoft",
+	PlatformEA:          "EA",
+	PlatformXbox:        "Xbox",
+	PlatformPlayStation: "PlayStation",
+	PlatformIndieGala:   "IndieGala",
+	PlatformAndroid:     "Android",
+	PlatformIOS:         "iOS",
+	PlatformOther:       "Other",
+}
+
+const (
+	DealGame        = "game"
+	DealDLC         = "dlc"
+	DealFreeWeekend = "free_weekend"
+	DealBeta        = "beta"
+	DealItem        = "item"
+	DealOther       = "other"
+)
+
+var DealTypes = []string{
+	DealGame,
+	DealDLC,
+	DealFreeWeekend,
+	DealBeta,
+	DealItem,
+	DealOther,
+}
+
+var DealTypeNames = map[string]string{
+	DealGame:        "Game",
+	DealDLC:         "DLC",
+	DealFreeWeekend: "Free weekend",
+	DealBeta:        "Beta",
+	DealItem:        "In-game item",
+	DealOther:       "Other",
+}
+
+// Matches titles like "[Steam] (Game) Title"
+var titleTagsRe = regexp.MustCompile(`^\s*\[([^\]]+)\]\s*(?:\(([^)]+)\))?`)
+
+type keywords struct {
+	value    string
+	keywords []string
+}
+
+// Checked in order, the first matching keyword wins
+var platformTags = []keywords{
+	{PlatformEpic, []string{"epic"}},
+	{PlatformSteam, []string{"steam"}},
+	{PlatformGOG, []string{"gog"}},
+	{PlatformItch, []string{"itch"}},
+	{PlatformPrime, []string{"prime", "amazon", "luna"}},
+	{PlatformUbisoft, []string{"ubisoft", "uplay"}},
+	{PlatformEA, []string{"ea app", "origin", "ea"}},
+	{PlatformXbox, []string{"xbox", "microsoft"}},
+	{PlatformPlayStation, []string{"playstation", "ps4", "ps5", "psn"}},
+	{PlatformIndieGala, []string{"indiegala"}},
+	{PlatformAndroid, []string{"android", "google play", "play store"}},
+	{PlatformIOS, []string{"ios", "app store", "iphone", "ipad"}},
+}
+
+var platformDomains = []keywords{
+	{PlatformSteam, []string{"steampowered.com", "steamcommunity.com"}},
+	{PlatformEpic, []string{"epicgames.com"}},
+	{PlatformGOG, []string{"gog.com"}},
+	{PlatformItch, []string{"itch.io"}},
+	{PlatformPrime, []string{"gaming.amazon.com", "luna.amazon.com"}},
+	{PlatformUbisoft, []string{"ubisoft.com", "ubi.com"}},
+	{PlatformEA, []string{"ea.com"}},
+	{PlatformXbox, []string{"xbox.com", "microsoft.com"}},
+	{PlatformPlayStation, []string{"playstation.com"}},
+	{PlatformIndieGala, []string{"indiegala.com"}},
+	{PlatformAndroid, []string{"play.google.com"}},
+	{PlatformIOS, []string{"apps.apple.com"}},
+}
+
+var dealTags = []keywords{
+	{DealFreeWeekend, []string{"free weekend", "weekend", "free to play for a limited time", "play for free"}},
+	{DealDLC, []string{"dlc", "add-on", "addon", "expansion", "soundtrack"}},
+	{DealBeta, []string{"beta", "alpha", "playtest"}},
+	{DealItem, []string{"item", "skin", "cosmetic", "loot", "in-game", "currency", "pack"}},
+	{DealGame, []string{"game"}},
+}
+
+// Classify fills the platform and the deal type of the link, which are left
+// as is when they are already set. The platform is derived from the
+// "[Platform]" prefix of the title and then from the domain of the link,
+// the deal type from the "(Type)" tag and then from the whole title.
+func Classify(link Link) Link {
+	tags := titleTagsRe.FindStringSubmatch(link.Title)
+	platformTag, dealTag := "", ""
+	if tags != nil {
+		platformTag, dealTag = tags[1], tags[2]
+	}
+
+	if link.Platform == "" {
+		link.Platform = matchKeywords(platformTags, platformTag, true)
+	}
+	if link.Platform == "" {
+		link.Platform = matchDomain(linkDomain(link))
+	}
+	if link.Platform == "" {
+		link.Platform = PlatformOther
+	}
+
+	if link.DealType == "" {
+		link.DealType = matchKeywords(dealTags, dealTag, false)
+	}
+	if link.DealType == "" {
+		link.DealType = matchKeywords(dealTags[:len(dealTags)-1], link.Title, true)
+	}
+	if link.DealType == "" {
+		link.DealType = DealOther
+	}
+	return link
+}
+
+func matchKeywords(values []keywords, text string, wholeWords bool) string {
+	text = strings.ToLower(text)
+	if text == "" {
+		return ""
+	}
+	for _, v := range values {
+		for _, keyword := range v.keywords {
+			if wholeWords && containsWord(text, keyword) {
+				return v.value
+			}
+			if !wholeWords && strings.Contains(text, keyword) {
+				return v.value
+			}
+		}
+	}
+	return ""
+}
+
+// containsWord reports whether text has keyword not surrounded by letters,
+// so that "ea" matches "[EA]" but not "[Steam]".
+func containsWord(text, keyword string) bool {
+	for i := 0; ; {
+		j := strings.Index(text[i:], keyword)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(keyword)
+		if (start == 0 || !isLetter(text[start-1])) && (end == len(text) || !isLetter(text[end])) {
+			return true
+		}
+		i = start + 1
+	}
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z'
+}
+
+func matchDomain(domain string) string {
+	domain = strings.ToLower(domain)
+	if domain == "" {
+		return ""
+	}
+	for _, v := range platformDomains {
+		for _, d := range v.keywords {
+			if domain == d || strings.HasSuffix(domain, "."+d) {
+				return v.value
+			}
+		}
+	}
+	return ""
+}
+
+func linkDomain(link Link) string {
+	if link.Domain != "" {
+		return link.Domain
+	}
+	u, err := url.Parse(link.Link)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
@@ -0,0 +1,41 @@
+package fetchers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Classify(t *testing.T) {
+	tests := []struct {
+		link     Link
+		platform string
+		dealType string
+	}{
+		{Link{Title: "[Steam] (Game) Nova Drift"}, PlatformSteam, DealGame},
+		{Link{Title: "[Epic Games] (DLC) Fortnite Skin Pack"}, PlatformEpic, DealDLC},
+		{Link{Title: "[GOG] (Game) Factory Escape Room"}, PlatformGOG, DealGame},
+		{Link{Title: "[itch.io] (Game) Tiny Rogues"}, PlatformItch, DealGame},
+		{Link{Title: "[Prime Gaming] (Game) Still There"}, PlatformPrime, DealGame},
+		{Link{Title: "[Ubisoft] (Free Weekend) The Crew 2"}, PlatformUbisoft, DealFreeWeekend},
+		{Link{Title: "[EA] (Game) Dead Space"}, PlatformEA, DealGame},
+		{Link{Title: "[Android] (Game) Princess Farmer"}, PlatformAndroid, DealGame},
+		{Link{Title: "[iOS] (Game) Alto's Odyssey"}, PlatformIOS, DealGame},
+		{Link{Title: "[Steam] (Beta) Deadlock Playtest"}, PlatformSteam, DealBeta},
+		{Link{Title: "[Steam] (In-game item) Warframe Glyph"}, PlatformSteam, DealItem},
+		{Link{Title: "[Steam] Free weekend for Tekken 8"}, PlatformSteam, DealFreeWeekend},
+		{
+			Link{Title: "[PSA] Still There is complimentary with Amazon Prime", Link: "https://luna.amazon.com/claims/home"},
+			PlatformPrime, DealOther,
+		},
+		{Link{Title: "Hogwarts Legacy", Link: "https://store.epicgames.com/en-US/p/hogwarts-legacy"}, PlatformEpic, DealOther},
+		{Link{Title: "Jetpack Joyride", Domain: "play.google.com"}, PlatformAndroid, DealOther},
+		{Link{Title: "[Discussion] Which launcher do you use?", Link: "/r/FreeGameFindings/comments/1/"}, PlatformOther, DealOther},
+		{Link{Title: "[Steam] (Game) Already classified", Platform: PlatformGOG, DealType: DealBeta}, PlatformGOG, DealBeta},
+	}
+	for _, test := range tests {
+		link := Classify(test.link)
+		assert.Equal(t, test.platform, link.Platform, test.link.Title)
+		assert.Equal(t, test.dealType, link.DealType, test.link.Title)
+	}
+}
@@ -0,0 +1,172 @@
+package fetchers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const EPIC_GAMES_STORE_URL = "https://store.epicgames.com/en-US/p/"
+
+var epicDealTypes = map[string]string{
+	"BASE_GAME": DealGame,
+	"BUNDLE":    DealGame,
+	"EDITION":   DealGame,
+	"DLC":       DealDLC,
+	"ADD_ON":    DealDLC,
+}
+
+type epicPromotions struct {
+	Data struct {
+		Catalog struct {
+			SearchStore struct {
+				Elements []epicElement `json:"elements"`
+			} `json:"searchStore"`
+		} `json:"Catalog"`
+	} `json:"data"`
+}
+
+type epicElement struct {
+	Title       string `json:"title"`
+	OfferType   string `json:"offerType"`
+	ProductSlug string `json:"productSlug"`
+	UrlSlug     string `json:"urlSlug"`
+	CatalogNs   struct {
+		Mappings []epicMapping `json:"mappings"`
+	} `json:"catalogNs"`
+	OfferMappings []epicMapping `json:"offerMappings"`
+	Promotions    *struct {
+		PromotionalOffers         []epicOffers `json:"promotionalOffers"`
+		UpcomingPromotionalOffers []epicOffers `json:"upcomingPromotionalOffers"`
+	} `json:"promotions"`
+}
+
+type epicMapping struct {
+	PageSlug string `json:"pageSlug"`
+	PageType string `json:"pageType"`
+}
+
+type epicOffers struct {
+	PromotionalOffers []epicOffer `json:"promotionalOffers"`
+}
+
+type epicOffer struct {
+	StartDate       time.Time `json:"startDate"`
+	EndDate         time.Time `json:"endDate"`
+	DiscountSetting struct {
+		DiscountType       string `json:"discountType"`
+		DiscountPercentage int    `json:"discountPercentage"`
+	} `json:"discountSetting"`
+}
+
+// isFree reports whether the offer brings the price down to zero.
+// Epic stores the percentage of the price left after the discount.
+func (o epicOffer) isFree() bool {
+	return o.DiscountSetting.DiscountType == "PERCENTAGE" && o.DiscountSetting.DiscountPercentage == 0
+}
+
+// slug returns the store page of the element, empty if it has none.
+func (e epicElement) slug() string {
+	for _, mappings := range [][]epicMapping{e.OfferMappings, e.CatalogNs.Mappings} {
+		for _, m := range mappings {
+			if m.PageType == "productHome" && m.PageSlug != "" {
+				return m.PageSlug
+			}
+		}
+	}
+	if slug := strings.TrimSuffix(e.ProductSlug, "/home"); slug != "" && slug != "[]" {
+		return slug
+	}
+	return e.UrlSlug
+}
+
+// EpicGamesFetcher reads the running free games from the promotions feed of
+// the Epic Games Store. Link.Date is the start of the promotion, upcoming
+// promotions are left out until they start.
+type EpicGamesFetcher struct {
+	url        string
+	httpClient *http.Client
+	storage    FetchStorage
+	now        func() time.Time
+}
+
+func NewEpicGamesFetcher(url string, httpClient *http.Client, storage FetchStorage) EpicGamesFetcher {
+	return EpicGamesFetcher{
+		url,
+		httpClient,
+		storage,
+		time.Now,
+	}
+}
+
+// Fetch returns the free promotions that have started and are still running
+// after sinceTime.
+func (f EpicGamesFetcher) Fetch(ctx context.Context, sinceTime time.Time) (Fetch, error) {
+	fetchId, err := f.storage.StoreFetch()
+	if err != nil {
+		return Fetch{}, fmt.Errorf("Error storing a fetch: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", f.url, nil)
+	if err != nil {
+		return Fetch{}, storeFetchError(f.storage, fetchId, fmt.Errorf("Error making request: %w", err))
+	}
+	req.Header.Set("Accept", "application/json")
+
+	body, err := doFetch(f.httpClient, f.storage, fetchId, req)
+	if err != nil {
+		return Fetch{}, fmt.Errorf("Error making request to Epic Games Store: %w", err)
+	}
+
+	var promotions epicPromotions
+	err = json.Unmarshal(body, &promotions)
+	if err != nil {
+		return Fetch{}, storeFetchError(f.storage, fetchId, fmt.Errorf("Error decoding response from Epic Games Store: %w", err))
+	}
+
+	now := f.now()
+	links := []Link{}
+	for _, element := range promotions.Data.Catalog.SearchStore.Elements {
+		if element.Promotions == nil {
+			continue
+		}
+		slug := element.slug()
+		if slug == "" {
+			continue
+		}
+
+		// The upcoming offers show up in the current ones once they start
+		for _, offers := range element.Promotions.PromotionalOffers {
+			for _, offer := range offers.PromotionalOffers {
+				if !offer.isFree() || offer.StartDate.After(now) || !offer.EndDate.After(sinceTime) {
+					continue
+				}
+				links = append(links, Classify(Link{
+					Link:      EPIC_GAMES_STORE_URL + slug,
+					Title:     element.Title,
+					Date:      offer.StartDate.UTC(),
+					ExpiresAt: offer.EndDate.UTC(),
+					Platform:  PlatformEpic,
+					DealType:  epicDealTypes[element.OfferType],
+				}))
+			}
+		}
+	}
+
+	sort.Slice(links, func(i, j int) bool {
+		return links[i].Date.After(links[j].Date)
+	})
+
+	for _, link := range links {
+		storePromotion(f.storage, fetchId, link)
+	}
+
+	if len(links) == 0 {
+		deleteEmptyFetch(f.storage, fetchId)
+	}
+	return Fetch{fetchId, links}, nil
+}
@@ -2,20 +2,28 @@ package fetchers
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
+	"github.com/freebies-telegram-bot/internal/db"
 )
 
 const (
 	PIKABU_URL             = "https://pikabu.ru/tag/%D0%A5%D0%B0%D0%BB%D1%8F%D0%B2%D0%B0/hot?cl=steam"
-	EPIC_GAMES_URL         = "https://beebom.com/epic-games-free-games-list/"
+	EPIC_GAMES_URL         = "https://store-site-backend-static-ipv4.ak.epicgames.com/freeGamesPromotions?locale=en-US&country=US&allowCountries=US"
 	FREE_GAME_FINDINGS_URL = "https://old.reddit.com/r/FreeGameFindings/new/"
+
+	REDDIT_URL                  = "https://www.reddit.com"
+	FREE_GAME_FINDINGS_JSON_URL = REDDIT_URL + "/r/FreeGameFindings/new.json"
 )
 
 type Fetch struct {
@@ -24,24 +32,51 @@ type Fetch struct {
 }
 
 type Link struct {
-	Link  string
-	Title string
-	Date  time.Time
+	Link      string
+	Title     string
+	Date      time.Time
+	ExpiresAt time.Time
+	Source    string
+
+	// Set by Classify
+	Platform string
+	DealType string
+
+	// Listing metadata, set by the sources that have it
+	Flair     string
+	Domain    string
+	Author    string
+	Score     int
+	Comments  int
+	Permalink string
+}
+
+// Fetcher is implemented by every source of freebie links.
+type Fetcher interface {
+	Fetch(ctx context.Context, sinceTime time.Time) (Fetch, error)
 }
 
 type FetchStorage interface {
 	StoreFetch() (int64, error)
 	StoreBody(fetchId int64, body string) error
+	AppendBody(fetchId int64, body string) error
 	StoreError(fetchId int64, errorStr string) error
 	DeleteFetch(id int64) error
 	DeleteFetchesOlderThan(deadline time.Time) (int64, error)
-	StorePost(fetch_id int64, link, title string, postedAt time.Time) error
+	StorePost(post db.Post) error
+	StorePromotion(post db.Post) error
 }
 
+// DefaultMaxPages limits the pages read by one fetch of paginated sources
+const DefaultMaxPages = 10
+
+// FreeGameFindingsFetcher scrapes the listing of old.reddit.com, following
+// its "next" button.
 type FreeGameFindingsFetcher struct {
 	url        string
 	httpClient *http.Client
 	storage    FetchStorage
+	maxPages   int
 }
 
 func NewFreeGameFindingsFetcher(url string, httpClient *http.Client, storage FetchStorage) FreeGameFindingsFetcher {
@@ -49,21 +84,66 @@ func NewFreeGameFindingsFetcher(url string, httpClient *http.Client, storage Fet
 		url,
 		httpClient,
 		storage,
+		DefaultMaxPages,
 	}
 }
 
-func (f FreeGameFindingsFetcher) Fetch(sinceTime time.Time) (Fetch, error) {
+// WithMaxPages returns a copy of the fetcher reading at most maxPages pages per fetch.
+func (f FreeGameFindingsFetcher) WithMaxPages(maxPages int) FreeGameFindingsFetcher {
+	f.maxPages = max(maxPages, 1)
+	return f
+}
+
+// Fetch reads the listing page by page until it reaches a post not newer
+// than sinceTime, the last page or the page cap. Every page body is stored
+// under the same fetch. A failed page after the first one ends the fetch
+// with the links read so far, the error is stored with the fetch.
+func (f FreeGameFindingsFetcher) Fetch(ctx context.Context, sinceTime time.Time) (Fetch, error) {
 	fetchId, err := f.storage.StoreFetch()
 	if err != nil {
 		return Fetch{}, fmt.Errorf("Error storing a fetch: %w", err)
 	}
 
-	req, err := http.NewRequest("GET", f.url, nil)
-	if err != nil {
-		if err := f.storage.StoreError(fetchId, err.Error()); err != nil {
-			return Fetch{}, fmt.Errorf("Error storing error for fetch '%d': %w", fetchId, err)
+	links := []Link{}
+	failed := false
+	pageUrl := f.url
+	for page := 0; page < max(f.maxPages, 1) && pageUrl != ""; page += 1 {
+		doc, err := f.fetchPage(ctx, fetchId, pageUrl, page)
+		if err != nil {
+			if page == 0 {
+				return Fetch{}, err
+			}
+			log.Printf("Stopped reading Free Game Findings at page %d: %s", page+1, err)
+			failed = true
+			break
+		}
+
+		pageLinks, reachedSinceTime := parseFreeGameFindingsPage(doc, sinceTime)
+		for _, link := range pageLinks {
+			storePost(f.storage, fetchId, link)
+		}
+		links = append(links, pageLinks...)
+		if reachedSinceTime {
+			break
+		}
+
+		pageUrl, err = nextPageUrl(doc, pageUrl)
+		if err != nil {
+			log.Println(err)
+			break
 		}
-		return Fetch{}, fmt.Errorf("Error making request: %w", err)
+	}
+
+	if len(links) == 0 && !failed {
+		deleteEmptyFetch(f.storage, fetchId)
+	}
+	return Fetch{fetchId, links}, nil
+}
+
+func (f FreeGameFindingsFetcher) fetchPage(ctx context.Context, fetchId int64, pageUrl string, page int) (*goquery.Document, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", pageUrl, nil)
+	if err != nil {
+		return nil, storeFetchError(f.storage, fetchId, fmt.Errorf("Error making request: %w", err))
 	}
 
 	// Emulate a standard Chrome browser request
@@ -80,41 +160,23 @@ func (f FreeGameFindingsFetcher) Fetch(sinceTime time.Time) (Fetch, error) {
 	req.Header.Set("Sec-Fetch-User", "?1")
 	req.Header.Set("Upgrade-Insecure-Requests", "1")
 
-	res, err := f.httpClient.Do(req)
-	if err != nil {
-		if err := f.storage.StoreError(fetchId, err.Error()); err != nil {
-			return Fetch{}, fmt.Errorf("Error storing error for fetch '%d': %w", fetchId, err)
-		}
-		return Fetch{}, fmt.Errorf("Error making request to Free Game Findings: %w", err)
-	}
-	defer res.Body.Close()
-	if res.StatusCode != 200 {
-		return Fetch{}, fmt.Errorf("status code error: %d %s", res.StatusCode, res.Status)
-
-	}
-
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		return Fetch{}, fmt.Errorf("Error reading body: %w", err)
-	}
-
-	err = f.storage.StoreBody(fetchId, string(body))
+	body, err := doFetchPage(f.httpClient, f.storage, fetchId, req, page)
 	if err != nil {
-		if err := f.storage.StoreError(fetchId, err.Error()); err != nil {
-			return Fetch{}, fmt.Errorf("Error storing error for fetch '%d': %w", fetchId, err)
-		}
-		return Fetch{}, fmt.Errorf("Error storing body for fetch '%d': %w", fetchId, err)
+		return nil, fmt.Errorf("Error making request to Free Game Findings: %w", err)
 	}
 
 	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
 	if err != nil {
-		if err := f.storage.StoreError(fetchId, err.Error()); err != nil {
-			return Fetch{}, fmt.Errorf("Error storing error for fetch '%d': %w", fetchId, err)
-		}
-		return Fetch{}, fmt.Errorf("Error reading response body from Free Game Findings: %w", err)
+		return nil, storeFetchError(f.storage, fetchId, fmt.Errorf("Error reading response body from Free Game Findings: %w", err))
 	}
+	return doc, nil
+}
 
+// parseFreeGameFindingsPage returns the links of the page newer than sinceTime
+// and whether the page has reached sinceTime.
+func parseFreeGameFindingsPage(doc *goquery.Document, sinceTime time.Time) ([]Link, bool) {
 	links := []Link{}
+	reachedSinceTime := false
 	doc.
 		Find("div#siteTable > :not(.promotedlink, .linkflair-modpost, .linkflair-Expired)").
 		Children().
@@ -126,9 +188,11 @@ func (f FreeGameFindingsFetcher) Fetch(sinceTime time.Time) (Fetch, error) {
 			date, err := time.Parse(time.RFC3339, datetime)
 			if err != nil {
 				log.Println(err)
+				reachedSinceTime = true
 				return false
 			}
 			if !date.UTC().After(sinceTime) {
+				reachedSinceTime = true
 				return false
 			}
 			// fmt.Println("Game:")
@@ -137,81 +201,92 @@ func (f FreeGameFindingsFetcher) Fetch(sinceTime time.Time) (Fetch, error) {
 			title := div.Find("p.title")
 			href, _ := title.Find("a").Attr("href")
 
-			link := Link{href, "", date}
-			links = append(links, link)
-
-			err = f.storage.StorePost(fetchId, link.Link, link.Title, link.Date)
-			if err != nil {
-				log.Println(err)
-			}
-
+			thing := div.Closest("div.thing")
+			flair, _ := title.Find("span.linkflairlabel").Attr("title")
+			domain, _ := thing.Attr("data-domain")
+			author, _ := thing.Attr("data-author")
+			permalink, _ := thing.Attr("data-permalink")
+
+			links = append(links, Classify(Link{
+				Link:      href,
+				Title:     strings.TrimSpace(title.Find("a.title").Text()),
+				Date:      date.UTC(),
+				Flair:     flair,
+				Domain:    domain,
+				Author:    author,
+				Score:     intAttr(thing, "data-score"),
+				Comments:  intAttr(thing, "data-comments-count"),
+				Permalink: REDDIT_URL + permalink,
+			}))
 			return true
 		})
+	return links, reachedSinceTime
+}
 
-	if len(links) == 0 {
-		err := f.storage.DeleteFetch(fetchId)
-		if err != nil {
-			log.Println(fmt.Errorf("Error deleting fetch id '%d': %w", fetchId, err))
-		}
+// intAttr returns the numeric attribute of the selection, 0 if it is missing.
+func intAttr(s *goquery.Selection, name string) int {
+	value, _ := s.Attr(name)
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0
 	}
-	return Fetch{fetchId, links}, nil
+	return number
 }
 
-type EpicGamesFetcher struct {
-	httpClient *http.Client
-}
+// nextPageUrl returns the url behind the "next" button, empty on the last page.
+func nextPageUrl(doc *goquery.Document, pageUrl string) (string, error) {
+	href, exists := doc.Find("div.nav-buttons span.next-button a").Attr("href")
+	if !exists || href == "" {
+		return "", nil
+	}
 
-func (f EpicGamesFetcher) Fetch(sinceTime time.Time) ([]string, error) {
-	res, err := f.httpClient.Get(EPIC_GAMES_URL)
+	base, err := url.Parse(pageUrl)
 	if err != nil {
-		return []string{}, err
+		return "", fmt.Errorf("Error parsing page url '%s': %w", pageUrl, err)
 	}
-	defer res.Body.Close()
-	if res.StatusCode != 200 {
-		log.Fatalf("status code error: %d %s", res.StatusCode, res.Status)
-	}
-
-	doc, err := goquery.NewDocumentFromReader(res.Body)
+	next, err := base.Parse(href)
 	if err != nil {
-		return []string{}, err
+		return "", fmt.Errorf("Error parsing next page url '%s': %w", href, err)
 	}
-
-	links := []string{}
-	doc.Find("figure.wp-block-table").Children().Find("tbody").Children().Each(func(i int, tr *goquery.Selection) {
-		td_games := tr.Children().First()
-		// td_dates := td_games.Next().Text()
-		// start := strings.Split(td_dates, "-")[0]
-		// game_start := time.
-
-		hrefs := td_games.Find("a").Map(func(i int, link *goquery.Selection) string {
-			result, _ := link.Attr("href")
-			return result
-		})
-		links = append(links, hrefs...)
-	})
-	return links[:5], nil
+	return next.String(), nil
 }
 
 type PikabuFetcher struct {
+	url        string
 	httpClient *http.Client
+	storage    FetchStorage
 }
 
-func (pf PikabuFetcher) Fetch(sinceTime time.Time) ([]string, error) {
-	res, err := pf.httpClient.Get(PIKABU_URL)
+func NewPikabuFetcher(url string, httpClient *http.Client, storage FetchStorage) PikabuFetcher {
+	return PikabuFetcher{
+		url,
+		httpClient,
+		storage,
+	}
+}
+
+func (pf PikabuFetcher) Fetch(ctx context.Context, sinceTime time.Time) (Fetch, error) {
+	fetchId, err := pf.storage.StoreFetch()
 	if err != nil {
-		return []string{}, err
+		return Fetch{}, fmt.Errorf("Error storing a fetch: %w", err)
 	}
-	defer res.Body.Close()
-	if res.StatusCode != 200 {
-		log.Fatalf("status code error: %d %s", res.StatusCode, res.Status)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", pf.url, nil)
+	if err != nil {
+		return Fetch{}, storeFetchError(pf.storage, fetchId, fmt.Errorf("Error making request: %w", err))
+	}
+
+	body, err := doFetch(pf.httpClient, pf.storage, fetchId, req)
+	if err != nil {
+		return Fetch{}, fmt.Errorf("Error making request to Pikabu: %w", err)
 	}
 
-	doc, err := goquery.NewDocumentFromReader(res.Body)
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
 	if err != nil {
-		return []string{}, err
+		return Fetch{}, storeFetchError(pf.storage, fetchId, fmt.Errorf("Error reading response body from Pikabu: %w", err))
 	}
 
-	links := []string{}
+	links := []Link{}
 	doc.Find("article").Each(func(i int, s *goquery.Selection) {
 		datetime, exists := s.Find(".caption.story__datetime.hint").Attr("datetime")
 		if !exists {
@@ -230,16 +305,106 @@ func (pf PikabuFetcher) Fetch(sinceTime time.Time) ([]string, error) {
 
 		title := s.Find(".story__title")
 		link, exists := title.Find("a").Attr("href")
-		log.Println(link)
 		if !exists {
 			log.Println("attribute href not found")
 			return
 		}
 
 		// TODO: pagination
-		links = append(links, link)
+		links = append(links, Classify(Link{Link: link, Title: strings.TrimSpace(title.Text()), Date: date}))
 	})
 
-	sort.Sort(sort.Reverse(sort.StringSlice(links)))
-	return links, nil
+	sort.Slice(links, func(i, j int) bool {
+		return links[i].Date.After(links[j].Date)
+	})
+
+	for _, link := range links {
+		storePost(pf.storage, fetchId, link)
+	}
+
+	if len(links) == 0 {
+		deleteEmptyFetch(pf.storage, fetchId)
+	}
+	return Fetch{fetchId, links}, nil
+}
+
+// doFetch performs the request and stores the response body under fetchId.
+// Every failure is recorded as the error of the fetch.
+func doFetch(httpClient *http.Client, storage FetchStorage, fetchId int64, req *http.Request) ([]byte, error) {
+	return doFetchPage(httpClient, storage, fetchId, req, 0)
+}
+
+// doFetchPage is doFetch for paginated sources. Bodies of the pages following
+// the first one are appended to the body of the fetch.
+func doFetchPage(httpClient *http.Client, storage FetchStorage, fetchId int64, req *http.Request, page int) ([]byte, error) {
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return nil, storeFetchError(storage, fetchId, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 200 {
+		return nil, storeFetchError(storage, fetchId, fmt.Errorf("status code error: %d %s", res.StatusCode, res.Status))
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, storeFetchError(storage, fetchId, fmt.Errorf("Error reading body: %w", err))
+	}
+
+	if page == 0 {
+		err = storage.StoreBody(fetchId, string(body))
+	} else {
+		err = storage.AppendBody(fetchId, string(body))
+	}
+	if err != nil {
+		return nil, storeFetchError(storage, fetchId, fmt.Errorf("Error storing body for fetch '%d': %w", fetchId, err))
+	}
+
+	return body, nil
+}
+
+func storePost(storage FetchStorage, fetchId int64, link Link) {
+	if err := storage.StorePost(linkPost(fetchId, link)); err != nil {
+		log.Println(err)
+	}
+}
+
+// storePromotion stores the link of a store promotion, a game given away
+// again gets the dates of the new promotion
+func storePromotion(storage FetchStorage, fetchId int64, link Link) {
+	if err := storage.StorePromotion(linkPost(fetchId, link)); err != nil {
+		log.Println(err)
+	}
+}
+
+func linkPost(fetchId int64, link Link) db.Post {
+	return db.Post{
+		FetchId:   fetchId,
+		Link:      link.Link,
+		Title:     link.Title,
+		PostedAt:  link.Date.UTC(),
+		ExpiresAt: link.ExpiresAt.UTC(),
+		Flair:     link.Flair,
+		Domain:    link.Domain,
+		Author:    link.Author,
+		Score:     link.Score,
+		Comments:  link.Comments,
+		Permalink: link.Permalink,
+		Platform:  link.Platform,
+		DealType:  link.DealType,
+	}
+}
+
+func storeFetchError(storage FetchStorage, fetchId int64, fetchErr error) error {
+	if err := storage.StoreError(fetchId, fetchErr.Error()); err != nil {
+		return fmt.Errorf("Error storing error for fetch '%d': %w", fetchId, err)
+	}
+	return fetchErr
+}
+
+func deleteEmptyFetch(storage FetchStorage, fetchId int64) {
+	err := storage.DeleteFetch(fetchId)
+	if err != nil {
+		log.Println(fmt.Errorf("Error deleting fetch id '%d': %w", fetchId, err))
+	}
 }
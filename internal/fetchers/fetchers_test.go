@@ -1,23 +1,359 @@
 package fetchers
 
 import (
+	"context"
+	_ "embed"
 	"fmt"
-	"log"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
+
+	"github.com/freebies-telegram-bot/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-// TODO: Setup tests
-// Add tests/reddit.html with snapshot of reddit page
-// Add tests/db.sqlite3 with test data: subscribers, links, posts
-// or create migrations in tests/ and create a fresh db for each test
+//go:embed epic.json
+var testEpicPromotions string
+
+//go:embed reddit_old_1.html
+var testFreeGameFindingsPage1 string
+
+//go:embed reddit_old_2.html
+var testFreeGameFindingsPage2 string
+
+//go:embed reddit_new_1.json
+var testRedditListingPage1 string
+
+//go:embed reddit_new_2.json
+var testRedditListingPage2 string
+
+type testStorage struct {
+	fetches int64
+	bodies  map[int64]string
+	errors  map[int64]string
+	deleted []int64
+	posts   []db.Post
+}
+
+func newTestStorage() *testStorage {
+	return &testStorage{
+		bodies: map[int64]string{},
+		errors: map[int64]string{},
+	}
+}
+
+func (s *testStorage) StoreFetch() (int64, error) {
+	s.fetches += 1
+	return s.fetches, nil
+}
+
+func (s *testStorage) StoreBody(fetchId int64, body string) error {
+	s.bodies[fetchId] = body
+	return nil
+}
+
+func (s *testStorage) AppendBody(fetchId int64, body string) error {
+	s.bodies[fetchId] += "\n" + body
+	return nil
+}
+
+func (s *testStorage) StoreError(fetchId int64, errorStr string) error {
+	s.errors[fetchId] = errorStr
+	return nil
+}
+
+func (s *testStorage) DeleteFetch(id int64) error {
+	s.deleted = append(s.deleted, id)
+	return nil
+}
+
+func (s *testStorage) DeleteFetchesOlderThan(deadline time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (s *testStorage) StorePost(post db.Post) error {
+	s.posts = append(s.posts, post)
+	return nil
+}
+
+func (s *testStorage) StorePromotion(post db.Post) error {
+	return s.StorePost(post)
+}
+
+func newTestFreeGameFindingsServer(requests *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests = append(*requests, r.URL.RequestURI())
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		switch r.URL.Query().Get("after") {
+		case "":
+			fmt.Fprint(w, testFreeGameFindingsPage1)
+		case "t3_a3":
+			fmt.Fprint(w, testFreeGameFindingsPage2)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
 
 func Test_FreeGameFindingsFetcher(t *testing.T) {
-	fetcher := FreeGameFindingsFetcher{}
-	sinceTime := time.Now().UTC().Add(-24 * 3 * time.Hour)
-	fetch, err := fetcher.Fetch(sinceTime)
-	if err != nil {
-		log.Fatalf("status code error: %s", err.Error())
+	requests := []string{}
+	httpServer := newTestFreeGameFindingsServer(&requests)
+	defer httpServer.Close()
+
+	storage := newTestStorage()
+	fetcher := NewFreeGameFindingsFetcher(httpServer.URL+"/r/FreeGameFindings/new/", &http.Client{}, storage)
+
+	sinceTime := time.Date(2026, 7, 8, 0, 0, 0, 0, time.UTC)
+	fetch, err := fetcher.Fetch(context.Background(), sinceTime)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"/r/FreeGameFindings/new/",
+		"/r/FreeGameFindings/new/?count=25&after=t3_a3",
+	}, requests)
+	assert.Equal(t, testFreeGameFindingsPage1+"\n"+testFreeGameFindingsPage2, storage.bodies[fetch.Id])
+
+	links := []string{}
+	for _, link := range fetch.Links {
+		links = append(links, link.Link)
+	}
+	assert.Equal(t, []string{
+		"https://store.steampowered.com/app/858210/Nova_Drift/",
+		"https://store.epicgames.com/en-US/p/princess-farmer",
+		"https://example.itch.io/tiny-rogues",
+	}, links)
+	assert.Equal(t, Link{
+		Link:      "https://store.steampowered.com/app/858210/Nova_Drift/",
+		Title:     "[Steam] (Game) Nova Drift",
+		Date:      time.Date(2026, 7, 9, 17, 0, 8, 0, time.UTC),
+		Platform:  PlatformSteam,
+		DealType:  DealGame,
+		Flair:     "Game",
+		Domain:    "store.steampowered.com",
+		Author:    "alice",
+		Score:     310,
+		Comments:  40,
+		Permalink: REDDIT_URL + "/r/FreeGameFindings/comments/a1/post/",
+	}, fetch.Links[0])
+
+	require.Len(t, storage.posts, 3)
+	assert.Equal(t, "[Epic Games] (Game) Princess Farmer", storage.posts[1].Title)
+	assert.Equal(t, "store.epicgames.com", storage.posts[1].Domain)
+	assert.Equal(t, 29, storage.posts[1].Comments)
+}
+
+func Test_FreeGameFindingsFetcherStopsAtPageCap(t *testing.T) {
+	requests := []string{}
+	httpServer := newTestFreeGameFindingsServer(&requests)
+	defer httpServer.Close()
+
+	storage := newTestStorage()
+	fetcher := NewFreeGameFindingsFetcher(httpServer.URL, &http.Client{}, storage).WithMaxPages(2)
+
+	fetch, err := fetcher.Fetch(context.Background(), time.Time{})
+	require.NoError(t, err)
+
+	assert.Len(t, requests, 2)
+	assert.Len(t, fetch.Links, 4)
+	assert.Empty(t, storage.errors)
+}
+
+func Test_FreeGameFindingsFetcherKeepsPagesBeforeError(t *testing.T) {
+	requests := 0
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests += 1
+		if r.URL.Query().Get("after") != "" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, testFreeGameFindingsPage1)
+	}))
+	defer httpServer.Close()
+
+	storage := newTestStorage()
+	fetcher := NewFreeGameFindingsFetcher(httpServer.URL, &http.Client{}, storage)
+
+	fetch, err := fetcher.Fetch(context.Background(), time.Time{})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, requests)
+	links := []string{}
+	for _, link := range fetch.Links {
+		links = append(links, link.Link)
+	}
+	assert.Equal(t, []string{
+		"https://store.steampowered.com/app/858210/Nova_Drift/",
+		"https://store.epicgames.com/en-US/p/princess-farmer",
+	}, links)
+	assert.Len(t, storage.posts, 2)
+	assert.Contains(t, storage.errors[fetch.Id], "500")
+	assert.Empty(t, storage.deleted)
+}
+
+func Test_RegistryRejectsDuplicateSources(t *testing.T) {
+	registry := NewRegistry()
+	if err := registry.Register("reddit", FreeGameFindingsFetcher{}, 0); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if err := registry.Register("reddit", FreeGameFindingsFetcher{}, 0); err == nil {
+		t.Fatal("expected an error for a duplicate source")
+	}
+
+	sources := registry.Sources()
+	if len(sources) != 1 || sources[0].Timeout != DefaultSourceTimeout {
+		t.Fatalf("unexpected sources: %+v", sources)
 	}
-	fmt.Println(fetch.Links)
+}
+
+func Test_EpicGamesFetcher(t *testing.T) {
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		fmt.Fprint(w, testEpicPromotions)
+	}))
+	defer httpServer.Close()
+
+	storage := newTestStorage()
+	fetcher := NewEpicGamesFetcher(httpServer.URL, &http.Client{}, storage)
+	fetcher.now = func() time.Time { return time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC) }
+
+	sinceTime := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	fetch, err := fetcher.Fetch(context.Background(), sinceTime)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(1), fetch.Id)
+	assert.Equal(t, testEpicPromotions, storage.bodies[fetch.Id])
+	// Alan Wake Remastered is upcoming
+	assert.Equal(t, []Link{
+		{
+			Link:      EPIC_GAMES_STORE_URL + "hogwarts-legacy",
+			Title:     "Hogwarts Legacy",
+			Date:      time.Date(2026, 10, 15, 15, 0, 0, 0, time.UTC),
+			ExpiresAt: time.Date(2026, 10, 22, 15, 0, 0, 0, time.UTC),
+			Platform:  PlatformEpic,
+			DealType:  DealGame,
+		},
+	}, fetch.Links)
+
+	require.Len(t, storage.posts, 1)
+	assert.Equal(t, fetch.Id, storage.posts[0].FetchId)
+	assert.Equal(t, EPIC_GAMES_STORE_URL+"hogwarts-legacy", storage.posts[0].Link)
+	assert.Equal(t, time.Date(2026, 10, 22, 15, 0, 0, 0, time.UTC), storage.posts[0].ExpiresAt)
+
+	fetch, err = fetcher.Fetch(context.Background(), time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Empty(t, fetch.Links)
+	assert.Equal(t, []int64{fetch.Id}, storage.deleted)
+
+	// Before the start of the current promotion
+	fetcher.now = func() time.Time { return time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC) }
+	fetch, err = fetcher.Fetch(context.Background(), time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Empty(t, fetch.Links)
+}
+
+func Test_EpicGamesFetcherStoresStatusError(t *testing.T) {
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer httpServer.Close()
+
+	storage := newTestStorage()
+	fetcher := NewEpicGamesFetcher(httpServer.URL, &http.Client{}, storage)
+
+	_, err := fetcher.Fetch(context.Background(), time.Time{})
+	require.Error(t, err)
+	assert.Contains(t, storage.errors[1], "503")
+	assert.Empty(t, storage.posts)
+}
+
+func newTestRedditServer(requests *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests = append(*requests, r.URL.Query().Get("after"))
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		switch r.URL.Query().Get("after") {
+		case "":
+			fmt.Fprint(w, testRedditListingPage1)
+		case "t3_1uw0004":
+			fmt.Fprint(w, testRedditListingPage2)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func Test_RedditFetcherFollowsAfterCursor(t *testing.T) {
+	requests := []string{}
+	httpServer := newTestRedditServer(&requests)
+	defer httpServer.Close()
+
+	storage := newTestStorage()
+	fetcher := NewRedditFetcher(httpServer.URL+"/r/FreeGameFindings/new.json", &http.Client{}, storage)
+
+	fetch, err := fetcher.Fetch(context.Background(), time.Unix(1783500000, 0).UTC())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"", "t3_1uw0004"}, requests)
+	assert.Equal(t, testRedditListingPage1+"\n"+testRedditListingPage2, storage.bodies[fetch.Id])
+
+	require.Len(t, fetch.Links, 4)
+	assert.Equal(t, Link{
+		Link:      "https://store.steampowered.com/app/858210/Nova_Drift/",
+		Title:     "[Steam] (Game) Nova Drift",
+		Date:      time.Unix(1783616408, 0).UTC(),
+		Platform:  PlatformSteam,
+		DealType:  DealGame,
+		Flair:     "Game",
+		Domain:    "store.steampowered.com",
+		Author:    "someone",
+		Score:     310,
+		Comments:  40,
+		Permalink: REDDIT_URL + "/r/FreeGameFindings/comments/1uw0002/steam_game_nova_drift/",
+	}, fetch.Links[0])
+	assert.Equal(t, "https://store.epicgames.com/en-US/p/princess-farmer", fetch.Links[1].Link)
+	assert.Equal(t, "https://example.itch.io/tiny-rogues", fetch.Links[2].Link)
+	assert.Equal(t, "/r/FreeGameFindings/comments/1uw0006/discussion_which_launcher_do_you_use?/", fetch.Links[3].Link)
+	assert.Len(t, storage.posts, 4)
+}
+
+func Test_RedditFetcherStopsAtSinceTime(t *testing.T) {
+	requests := []string{}
+	httpServer := newTestRedditServer(&requests)
+	defer httpServer.Close()
+
+	storage := newTestStorage()
+	fetcher := NewRedditFetcher(httpServer.URL, &http.Client{}, storage)
+
+	fetch, err := fetcher.Fetch(context.Background(), time.Unix(1783609400, 0).UTC())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{""}, requests)
+	require.Len(t, fetch.Links, 2)
+	assert.Equal(t, "[Epic Games] (Game) Princess Farmer", fetch.Links[1].Title)
+}
+
+func Test_RedditFetcherKeepsPagesBeforeError(t *testing.T) {
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("after") != "" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		fmt.Fprint(w, testRedditListingPage1)
+	}))
+	defer httpServer.Close()
+
+	storage := newTestStorage()
+	fetcher := NewRedditFetcher(httpServer.URL, &http.Client{}, storage)
+
+	fetch, err := fetcher.Fetch(context.Background(), time.Unix(1783500000, 0).UTC())
+	require.NoError(t, err)
+
+	require.Len(t, fetch.Links, 2)
+	assert.Equal(t, "https://store.steampowered.com/app/858210/Nova_Drift/", fetch.Links[0].Link)
+	assert.Len(t, storage.posts, 2)
+	assert.Contains(t, storage.errors[fetch.Id], "500")
+	assert.Empty(t, storage.deleted)
 }
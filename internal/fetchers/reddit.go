@@ -0,0 +1,171 @@
+package fetchers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const RedditPageLimit = 100
+
+// Posts with these flairs are never links to freebies
+var redditSkippedFlairs = map[string]bool{
+	"modpost": true,
+	"Expired": true,
+}
+
+type redditListing struct {
+	Data struct {
+		After    string `json:"after"`
+		Children []struct {
+			Data redditPost `json:"data"`
+		} `json:"children"`
+	} `json:"data"`
+}
+
+type redditPost struct {
+	Title             string  `json:"title"`
+	LinkFlairText     string  `json:"link_flair_text"`
+	LinkFlairCssClass string  `json:"link_flair_css_class"`
+	Author            string  `json:"author"`
+	Score             int     `json:"score"`
+	NumComments       int     `json:"num_comments"`
+	Domain            string  `json:"domain"`
+	Permalink         string  `json:"permalink"`
+	Url               string  `json:"url"`
+	IsSelf            bool    `json:"is_self"`
+	Stickied          bool    `json:"stickied"`
+	CreatedUtc        float64 `json:"created_utc"`
+}
+
+// RedditFetcher reads new posts of a subreddit from its JSON listing,
+// e.g. https://www.reddit.com/r/FreeGameFindings/new.json
+type RedditFetcher struct {
+	url        string
+	httpClient *http.Client
+	storage    FetchStorage
+	maxPages   int
+}
+
+func NewRedditFetcher(url string, httpClient *http.Client, storage FetchStorage) RedditFetcher {
+	return RedditFetcher{
+		url,
+		httpClient,
+		storage,
+		DefaultMaxPages,
+	}
+}
+
+// WithMaxPages returns a copy of the fetcher reading at most maxPages pages per fetch.
+func (f RedditFetcher) WithMaxPages(maxPages int) RedditFetcher {
+	f.maxPages = max(maxPages, 1)
+	return f
+}
+
+// Fetch follows the `after` cursor of the listing until it reaches a post
+// not newer than sinceTime or the page cap. A failed page after the first one
+// ends the fetch with the links read so far, the error is stored with the fetch.
+func (f RedditFetcher) Fetch(ctx context.Context, sinceTime time.Time) (Fetch, error) {
+	fetchId, err := f.storage.StoreFetch()
+	if err != nil {
+		return Fetch{}, fmt.Errorf("Error storing a fetch: %w", err)
+	}
+
+	links := []Link{}
+	failed := false
+	after := ""
+	for page := 0; page < max(f.maxPages, 1); page += 1 {
+		listing, err := f.fetchPage(ctx, fetchId, after, page)
+		if err != nil {
+			if page == 0 {
+				return Fetch{}, err
+			}
+			log.Printf("Stopped reading Reddit at page %d: %s", page+1, err)
+			failed = true
+			break
+		}
+
+		reachedSinceTime := false
+		for _, child := range listing.Data.Children {
+			post := child.Data
+			date := time.Unix(int64(post.CreatedUtc), 0).UTC()
+			if !date.After(sinceTime) {
+				if post.Stickied {
+					continue
+				}
+				reachedSinceTime = true
+				break
+			}
+			if redditSkippedFlairs[post.LinkFlairCssClass] {
+				continue
+			}
+
+			link := Link{
+				Link:      post.Url,
+				Title:     post.Title,
+				Date:      date,
+				Flair:     post.LinkFlairText,
+				Domain:    post.Domain,
+				Author:    post.Author,
+				Score:     post.Score,
+				Comments:  post.NumComments,
+				Permalink: REDDIT_URL + post.Permalink,
+			}
+			// Self posts are kept relative like on old.reddit.com
+			if post.IsSelf {
+				link.Link = post.Permalink
+			}
+			link = Classify(link)
+			links = append(links, link)
+			storePost(f.storage, fetchId, link)
+		}
+
+		after = listing.Data.After
+		if reachedSinceTime || after == "" {
+			break
+		}
+	}
+
+	if len(links) == 0 && !failed {
+		deleteEmptyFetch(f.storage, fetchId)
+	}
+	return Fetch{fetchId, links}, nil
+}
+
+func (f RedditFetcher) fetchPage(ctx context.Context, fetchId int64, after string, page int) (redditListing, error) {
+	pageUrl, err := url.Parse(f.url)
+	if err != nil {
+		return redditListing{}, storeFetchError(f.storage, fetchId, fmt.Errorf("Error parsing url '%s': %w", f.url, err))
+	}
+	query := pageUrl.Query()
+	query.Set("limit", strconv.Itoa(RedditPageLimit))
+	query.Set("raw_json", "1")
+	if after != "" {
+		query.Set("after", after)
+	}
+	pageUrl.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", pageUrl.String(), nil)
+	if err != nil {
+		return redditListing{}, storeFetchError(f.storage, fetchId, fmt.Errorf("Error making request: %w", err))
+	}
+	req.Header.Set("User-Agent", "freebies-telegram-bot/1.0")
+	req.Header.Set("Accept", "application/json")
+
+	body, err := doFetchPage(f.httpClient, f.storage, fetchId, req, page)
+	if err != nil {
+		return redditListing{}, fmt.Errorf("Error making request to Reddit: %w", err)
+	}
+
+	var listing redditListing
+	err = json.Unmarshal(body, &listing)
+	if err != nil {
+		return redditListing{}, storeFetchError(f.storage, fetchId, fmt.Errorf("Error decoding response from Reddit: %w", err))
+	}
+	return listing, nil
+}
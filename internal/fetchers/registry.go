@@ -0,0 +1,42 @@
+package fetchers
+
+import (
+	"fmt"
+	"time"
+)
+
+const DefaultSourceTimeout = 30 * time.Second
+
+// Source is a named fetcher registered in a Registry.
+type Source struct {
+	Name    string
+	Fetcher Fetcher
+	Timeout time.Duration
+}
+
+// Registry keeps the sources of freebie links in registration order.
+type Registry struct {
+	sources []Source
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) Register(name string, fetcher Fetcher, timeout time.Duration) error {
+	for _, source := range r.sources {
+		if source.Name == name {
+			return fmt.Errorf("Source '%s' is already registered", name)
+		}
+	}
+	if timeout <= 0 {
+		timeout = DefaultSourceTimeout
+	}
+
+	r.sources = append(r.sources, Source{name, fetcher, timeout})
+	return nil
+}
+
+func (r *Registry) Sources() []Source {
+	return append([]Source(nil), r.sources...)
+}
@@ -0,0 +1,148 @@
+package locale
+
+var en = map[Key]string{
+	Welcome:        "Hey! I'll be posting new freebies from r/FreeGameFindings, the Epic Games Store and Pikabu. 🙂",
+	UnknownCommand: "Unknown command 🧐. Type /help",
+	HelpHeader:     "Here is what I can do:",
+	Subscribed:     "I'll be posting new freebies from now on as soon as I find some. 😉",
+	Unsubscribed:   "I won't be posting new freebies anymore. 😐",
+
+	NewLinksHeader: "Just found some new freebies for you 😉",
+	LinksHeader:    "Here are some freebies for you 😉",
+	DigestHeader:   "Here is your digest of new freebies 😉",
+	NoLinksToday:   "No freebies for today 😕",
+	NoLinks:        "No freebies so far 😕",
+
+	GetIt:        "🎁 Get it",
+	SourceThread: "Source thread",
+	Thread:       "thread",
+	Starts:       "starts %s",
+	Until:        "until %s",
+	DateLayout:   "2 Jan 15:04 MST",
+
+	PlatformsPrompt: "Choose the platforms you want to get freebies for 🎮",
+	PlatformOn:      "%s freebies are on",
+	PlatformOff:     "%s freebies are off",
+
+	BatchOn:  "I'll be sending new freebies in a single digest message 👌",
+	BatchOff: "I'll be sending every freebie in a separate message 👌",
+
+	FilterUsage: `Usage:
+/filter add <word> - mute freebies with the word in the title or the link
+/filter remove <word> - remove the word
+/filter list - show your words
+/filter mode allow - get only freebies with your words
+/filter mode deny - mute freebies with your words`,
+	FilterUsageAllow: `Usage:
+/filter add <word> - get freebies with the word in the title or the link
+/filter remove <word> - remove the word
+/filter list - show your words
+/filter mode allow - get only freebies with your words
+/filter mode deny - mute freebies with your words`,
+	KeywordAdded:    "Added \"%s\" to your words 👌",
+	KeywordRemoved:  "Removed \"%s\" from your words 👌",
+	KeywordMissing:  "\"%s\" is not in your words 🧐",
+	NoKeywords:      "You have no words yet. Add one with /filter add <word>",
+	KeywordsMuted:   "Freebies with these words are muted:",
+	KeywordsAllowed: "You get only freebies with these words:",
+	ModeAllow:       "From now on you get only freebies with your words 👌",
+	ModeDeny:        "From now on freebies with your words are muted 👌",
+
+	DigestUsage: `Usage:
+/digest off - get new freebies as soon as I find them
+/digest daily [HH:MM] - get new freebies once a day
+/digest weekly [day] [HH:MM] - get new freebies once a week
+Times are in your timezone, see /timezone.`,
+	DigestDaily:   "I'll be sending new freebies once a day at %s 👌",
+	DigestWeekly:  "I'll be sending new freebies every %s at %s 👌",
+	DigestInstant: "I'll be sending new freebies as soon as I find them 👌",
+
+	TimezoneUsage: `Usage:
+/timezone <name> - set your timezone, e.g. /timezone Europe/Moscow or /timezone UTC+3`,
+	TimezoneCurrent: "Your timezone is %s",
+	TimezoneUnknown: "I don't know the timezone \"%s\" 🧐",
+	TimezoneSet:     "Your timezone is %s now 👌",
+
+	QuietUsage: `Usage:
+/quiet 23:00-08:00 - hold new freebies during these hours
+/quiet off - get new freebies at any time`,
+	QuietOff: "I'll be sending new freebies at any time 👌",
+	QuietSet: "I'll hold new freebies from %s to %s in your timezone 👌",
+
+	LanguagePrompt: "Choose your language",
+	LanguageSet:    "I'll be speaking English from now on 👌",
+
+	AdminsOnly: "Only administrators of the group can change its settings 🙅",
+
+	ClaimedButton:       "✅ Claimed",
+	NotInterestedButton: "🙈 Not interested",
+	HidePlatformButton:  "🚫 Hide %s",
+	ShowPlatformButton:  "👀 Show %s",
+	FeedbackSaved:       "Got it 👌",
+	FeedbackCleared:     "Cleared 👌",
+	ClaimedHeader:       "Freebies you have claimed 🎁",
+	NoClaimed:           "You haven't claimed anything yet. Tap ✅ Claimed under a freebie to keep track of it, or its number under a list of freebies first",
+	PostGone:            "This freebie is not in my history anymore 🤷",
+
+	SearchUsage:      "Type what to look for after the command, e.g. /search witcher",
+	SearchHeader:     "Found %d freebies for \"%s\", page %d of %d",
+	NoSearchResults:  "Nothing found for \"%s\" 🤷",
+	SearchExpired:    "The search is gone, send /search again",
+	SearchPrevious:   "◀️ Back",
+	SearchNext:       "Next ▶️",
+	Posted:           "posted %s",
+	SearchDateLayout: "2 Jan 2006",
+
+	OperatorsOnly:         "Only the operators of the bot can do that 🙅",
+	StatsReport:           "Subscribers: %d\n\nLast 24 hours:\nNew posts: %d\nFetches: %d, failed %d\nMessages sent: %d, failed %d\n\nMessages waiting: %d",
+	SubscribersReport:     "Subscribers: %d\nGroups: %d\nDigests: %d\n\nLast 7 days:\nSubscribed: %d\nUnsubscribed: %d\nBlocked the bot: %d",
+	BroadcastUsage:        "Type the message after the command, e.g. /broadcast The bot will be down tonight",
+	BroadcastPreview:      "Subscribers will get this message:",
+	BroadcastSendButton:   "📣 Send to %d subscribers",
+	BroadcastCancelButton: "❌ Cancel",
+	BroadcastQueued:       "The message is queued for %d subscribers 📣",
+	BroadcastCancelled:    "Cancelled 👌",
+	BroadcastExpired:      "This broadcast has expired, send /broadcast again",
+	FetchStarted:          "Fetching new freebies now 🔄",
+	LastFetch:             "Last fetch #%d at %s",
+	LastFetchError:        "Last error, fetch #%d at %s:\n%s",
+	NoFetches:             "Nothing has been fetched yet",
+	NoFetchErrors:         "No fetch has failed 👌",
+	SourcesHeader:         "Sources, /mute <source> to mute or unmute one:",
+	MuteUsage:             "Type a single source after the command, e.g. /mute pikabu",
+	SourceUnknown:         "Unknown source \"%s\", the sources are: %s",
+	SourceMuted:           "%s is muted 🔇",
+	SourceUnmuted:         "%s is fetched again 🔊",
+	ChannelUnavailable:    "Channel %d can't get messages: %s\nIt gets no freebies until the bot is restarted ⚠️",
+
+	"weekday_0": "Sunday",
+	"weekday_1": "Monday",
+	"weekday_2": "Tuesday",
+	"weekday_3": "Wednesday",
+	"weekday_4": "Thursday",
+	"weekday_5": "Friday",
+	"weekday_6": "Saturday",
+
+	"command_today":     "List new freebies for today",
+	"command_yesterday": "List new freebies since yesterday",
+	"command_week":      "List new freebies 1 week",
+	"command_month":     "List new freebies 30 days",
+	"command_receive":   "Toggle posting of new freebies",
+	"command_platforms": "Choose platforms of freebies",
+	"command_filter":    "Mute or allow freebies by words",
+	"command_batch":     "Toggle getting new freebies in a single message",
+	"command_digest":    "Get new freebies daily or weekly",
+	"command_timezone":  "Set your timezone",
+	"command_quiet":     "Set hours without new freebies",
+	"command_language":  "Choose the language of the bot",
+	"command_claimed":   "List the freebies you have claimed",
+	"command_help":      "List the commands",
+	"command_search":    "Search all the freebies found before",
+
+	"command_stats":       "Show how the bot is doing",
+	"command_subscribers": "Show the subscribers",
+	"command_broadcast":   "Send a message to every subscriber",
+	"command_fetchnow":    "Fetch new freebies now",
+	"command_lastfetch":   "Show the last fetch error",
+	"command_mute":        "Mute or unmute a source",
+}
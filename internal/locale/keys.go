@@ -0,0 +1,100 @@
+package locale
+
+const (
+	Welcome        Key = "welcome"
+	UnknownCommand Key = "unknown_command"
+	HelpHeader     Key = "help_header"
+	Subscribed     Key = "subscribed"
+	Unsubscribed   Key = "unsubscribed"
+
+	NewLinksHeader Key = "new_links_header"
+	LinksHeader    Key = "links_header"
+	DigestHeader   Key = "digest_header"
+	NoLinksToday   Key = "no_links_today"
+	NoLinks        Key = "no_links"
+
+	GetIt        Key = "get_it"
+	SourceThread Key = "source_thread"
+	Thread       Key = "thread"
+	Starts       Key = "starts"
+	Until        Key = "until"
+	DateLayout   Key = "date_layout"
+
+	PlatformsPrompt Key = "platforms_prompt"
+	PlatformOn      Key = "platform_on"
+	PlatformOff     Key = "platform_off"
+
+	BatchOn  Key = "batch_on"
+	BatchOff Key = "batch_off"
+
+	FilterUsage      Key = "filter_usage"
+	FilterUsageAllow Key = "filter_usage_allow"
+	KeywordAdded     Key = "keyword_added"
+	KeywordRemoved   Key = "keyword_removed"
+	KeywordMissing   Key = "keyword_missing"
+	NoKeywords       Key = "no_keywords"
+	KeywordsMuted    Key = "keywords_muted"
+	KeywordsAllowed  Key = "keywords_allowed"
+	ModeAllow        Key = "mode_allow"
+	ModeDeny         Key = "mode_deny"
+
+	DigestUsage   Key = "digest_usage"
+	DigestDaily   Key = "digest_daily"
+	DigestWeekly  Key = "digest_weekly"
+	DigestInstant Key = "digest_instant"
+
+	TimezoneUsage   Key = "timezone_usage"
+	TimezoneCurrent Key = "timezone_current"
+	TimezoneUnknown Key = "timezone_unknown"
+	TimezoneSet     Key = "timezone_set"
+
+	QuietUsage Key = "quiet_usage"
+	QuietOff   Key = "quiet_off"
+	QuietSet   Key = "quiet_set"
+
+	LanguagePrompt Key = "language_prompt"
+	LanguageSet    Key = "language_set"
+
+	AdminsOnly Key = "admins_only"
+
+	ClaimedButton       Key = "claimed_button"
+	NotInterestedButton Key = "not_interested_button"
+	HidePlatformButton  Key = "hide_platform_button"
+	ShowPlatformButton  Key = "show_platform_button"
+	FeedbackSaved       Key = "feedback_saved"
+	FeedbackCleared     Key = "feedback_cleared"
+	ClaimedHeader       Key = "claimed_header"
+	NoClaimed           Key = "no_claimed"
+	PostGone            Key = "post_gone"
+
+	SearchUsage      Key = "search_usage"
+	SearchHeader     Key = "search_header"
+	NoSearchResults  Key = "no_search_results"
+	SearchExpired    Key = "search_expired"
+	SearchPrevious   Key = "search_previous"
+	SearchNext       Key = "search_next"
+	Posted           Key = "posted"
+	SearchDateLayout Key = "search_date_layout"
+
+	OperatorsOnly         Key = "operators_only"
+	StatsReport           Key = "stats_report"
+	SubscribersReport     Key = "subscribers_report"
+	BroadcastUsage        Key = "broadcast_usage"
+	BroadcastPreview      Key = "broadcast_preview"
+	BroadcastSendButton   Key = "broadcast_send_button"
+	BroadcastCancelButton Key = "broadcast_cancel_button"
+	BroadcastQueued       Key = "broadcast_queued"
+	BroadcastCancelled    Key = "broadcast_cancelled"
+	BroadcastExpired      Key = "broadcast_expired"
+	FetchStarted          Key = "fetch_started"
+	LastFetch             Key = "last_fetch"
+	LastFetchError        Key = "last_fetch_error"
+	NoFetches             Key = "no_fetches"
+	NoFetchErrors         Key = "no_fetch_errors"
+	SourcesHeader         Key = "sources_header"
+	MuteUsage             Key = "mute_usage"
+	SourceUnknown         Key = "source_unknown"
+	SourceMuted           Key = "source_muted"
+	SourceUnmuted         Key = "source_unmuted"
+	ChannelUnavailable    Key = "channel_unavailable"
+)
@@ -0,0 +1,66 @@
+package locale
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	English = "en"
+	Russian = "ru"
+
+	// Used for the keys missing in other catalogs
+	Default = English
+)
+
+// Languages lists every supported language in the order it is shown to users
+var Languages = []string{English, Russian}
+
+var LanguageNames = map[string]string{
+	English: "English",
+	Russian: "Русский",
+}
+
+// Key identifies a message of the catalogs
+type Key string
+
+var catalogs = map[string]map[Key]string{
+	English: en,
+	Russian: ru,
+}
+
+// Get returns the message in lang formatted with args like fmt.Sprintf.
+// Unknown languages and missing messages fall back to English.
+func Get(lang string, key Key, args ...any) string {
+	message, ok := catalogs[lang][key]
+	if !ok {
+		message, ok = catalogs[Default][key]
+	}
+	if !ok {
+		return string(key)
+	}
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+// Match returns the supported language of an IETF tag like "ru-RU",
+// empty if there is none.
+func Match(tag string) string {
+	base, _, _ := strings.Cut(strings.ToLower(tag), "-")
+	if _, ok := catalogs[base]; ok {
+		return base
+	}
+	return ""
+}
+
+func Weekday(lang string, day time.Weekday) string {
+	return Get(lang, Key(fmt.Sprintf("weekday_%d", day)))
+}
+
+// CommandDescription is shown by Telegram in the command menu
+func CommandDescription(lang, command string) string {
+	return Get(lang, Key("command_"+command))
+}
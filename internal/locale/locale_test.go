@@ -0,0 +1,33 @@
+package locale
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CatalogsHaveEveryMessage(t *testing.T) {
+	for _, lang := range Languages {
+		for key, message := range catalogs[Default] {
+			translated, ok := catalogs[lang][key]
+			if assert.True(t, ok, "%s has no message '%s'", lang, key) {
+				assert.Equal(t, strings.Count(message, "%"), strings.Count(translated, "%"), "%s message '%s' has other arguments", lang, key)
+			}
+		}
+	}
+}
+
+func Test_Get(t *testing.T) {
+	assert.Equal(t, "Слово \"dlc\" добавлено 👌", Get(Russian, KeywordAdded, "dlc"))
+	assert.Equal(t, "Added \"dlc\" to your words 👌", Get("de", KeywordAdded, "dlc"))
+	assert.Equal(t, "пятница", Weekday(Russian, time.Friday))
+}
+
+func Test_Match(t *testing.T) {
+	assert.Equal(t, Russian, Match("ru-RU"))
+	assert.Equal(t, English, Match("en"))
+	assert.Equal(t, "", Match("de"))
+	assert.Equal(t, "", Match(""))
+}
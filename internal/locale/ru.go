@@ -0,0 +1,148 @@
+package locale
+
+var ru = map[Key]string{
+	Welcome:        "Привет! Я буду присылать новые халявные игры с r/FreeGameFindings, Epic Games Store и Пикабу. 🙂",
+	UnknownCommand: "Неизвестная команда 🧐. Набери /help",
+	HelpHeader:     "Вот что я умею:",
+	Subscribed:     "С этого момента я буду присылать новую халяву, как только найду. 😉",
+	Unsubscribed:   "Я больше не буду присылать новую халяву. 😐",
+
+	NewLinksHeader: "Нашёл для тебя новую халяву 😉",
+	LinksHeader:    "Вот немного халявы для тебя 😉",
+	DigestHeader:   "Твоя подборка новой халявы 😉",
+	NoLinksToday:   "Сегодня халявы нет 😕",
+	NoLinks:        "Пока халявы нет 😕",
+
+	GetIt:        "🎁 Забрать",
+	SourceThread: "Обсуждение",
+	Thread:       "обсуждение",
+	Starts:       "с %s",
+	Until:        "до %s",
+	DateLayout:   "02.01 15:04 MST",
+
+	PlatformsPrompt: "Выбери платформы, халяву для которых ты хочешь получать 🎮",
+	PlatformOn:      "Халява для %s включена",
+	PlatformOff:     "Халява для %s выключена",
+
+	BatchOn:  "Я буду присылать новую халяву одним сообщением 👌",
+	BatchOff: "Я буду присылать каждую раздачу отдельным сообщением 👌",
+
+	FilterUsage: `Использование:
+/filter add <слово> - скрывать халяву со словом в названии или ссылке
+/filter remove <слово> - удалить слово
+/filter list - показать твои слова
+/filter mode allow - получать только халяву с твоими словами
+/filter mode deny - скрывать халяву с твоими словами`,
+	FilterUsageAllow: `Использование:
+/filter add <слово> - получать халяву со словом в названии или ссылке
+/filter remove <слово> - удалить слово
+/filter list - показать твои слова
+/filter mode allow - получать только халяву с твоими словами
+/filter mode deny - скрывать халяву с твоими словами`,
+	KeywordAdded:    "Слово \"%s\" добавлено 👌",
+	KeywordRemoved:  "Слово \"%s\" удалено 👌",
+	KeywordMissing:  "Слова \"%s\" нет в твоём списке 🧐",
+	NoKeywords:      "У тебя пока нет слов. Добавь с помощью /filter add <слово>",
+	KeywordsMuted:   "Халява с этими словами скрыта:",
+	KeywordsAllowed: "Ты получаешь только халяву с этими словами:",
+	ModeAllow:       "Теперь ты получаешь только халяву с твоими словами 👌",
+	ModeDeny:        "Теперь халява с твоими словами скрыта 👌",
+
+	DigestUsage: `Использование:
+/digest off - получать новую халяву сразу
+/digest daily [ЧЧ:ММ] - получать новую халяву раз в день
+/digest weekly [день] [ЧЧ:ММ] - получать новую халяву раз в неделю
+Время указывается в твоём часовом поясе, смотри /timezone.`,
+	DigestDaily:   "Я буду присылать новую халяву раз в день в %s 👌",
+	DigestWeekly:  "Я буду присылать новую халяву каждую неделю (%s) в %s 👌",
+	DigestInstant: "Я буду присылать новую халяву, как только найду 👌",
+
+	TimezoneUsage: `Использование:
+/timezone <название> - установить часовой пояс, например /timezone Europe/Moscow или /timezone UTC+3`,
+	TimezoneCurrent: "Твой часовой пояс: %s",
+	TimezoneUnknown: "Я не знаю часовой пояс \"%s\" 🧐",
+	TimezoneSet:     "Теперь твой часовой пояс: %s 👌",
+
+	QuietUsage: `Использование:
+/quiet 23:00-08:00 - не присылать халяву в эти часы
+/quiet off - присылать халяву в любое время`,
+	QuietOff: "Я буду присылать халяву в любое время 👌",
+	QuietSet: "Я не буду присылать халяву с %s до %s по твоему времени 👌",
+
+	LanguagePrompt: "Выбери язык",
+	LanguageSet:    "Теперь я говорю по-русски 👌",
+
+	AdminsOnly: "Только администраторы группы могут менять её настройки 🙅",
+
+	ClaimedButton:       "✅ Забрал",
+	NotInterestedButton: "🙈 Не интересно",
+	HidePlatformButton:  "🚫 Скрыть %s",
+	ShowPlatformButton:  "👀 Показывать %s",
+	FeedbackSaved:       "Понял 👌",
+	FeedbackCleared:     "Отменено 👌",
+	ClaimedHeader:       "Халява, которую ты забрал 🎁",
+	NoClaimed:           "Ты пока ничего не забрал. Нажми ✅ Забрал под раздачей, чтобы её отметить, а в списке сначала нажми её номер",
+	PostGone:            "Этой раздачи уже нет в моей истории 🤷",
+
+	SearchUsage:      "Напиши, что искать, после команды, например /search witcher",
+	SearchHeader:     "Нашёл %d раздач по запросу \"%s\", страница %d из %d",
+	NoSearchResults:  "По запросу \"%s\" ничего не нашлось 🤷",
+	SearchExpired:    "Поиск устарел, отправь /search ещё раз",
+	SearchPrevious:   "◀️ Назад",
+	SearchNext:       "Дальше ▶️",
+	Posted:           "опубликовано %s",
+	SearchDateLayout: "02.01.2006",
+
+	OperatorsOnly:         "Это могут делать только операторы бота 🙅",
+	StatsReport:           "Подписчиков: %d\n\nЗа 24 часа:\nНовых постов: %d\nЗагрузок: %d, с ошибкой %d\nОтправлено сообщений: %d, не отправлено %d\n\nСообщений в очереди: %d",
+	SubscribersReport:     "Подписчиков: %d\nГрупп: %d\nС подборками: %d\n\nЗа 7 дней:\nПодписались: %d\nОтписались: %d\nЗаблокировали бота: %d",
+	BroadcastUsage:        "Напиши сообщение после команды, например /broadcast Ночью бот не будет работать",
+	BroadcastPreview:      "Подписчики получат это сообщение:",
+	BroadcastSendButton:   "📣 Отправить %d подписчикам",
+	BroadcastCancelButton: "❌ Отмена",
+	BroadcastQueued:       "Сообщение поставлено в очередь для %d подписчиков 📣",
+	BroadcastCancelled:    "Отменено 👌",
+	BroadcastExpired:      "Эта рассылка устарела, отправь /broadcast ещё раз",
+	FetchStarted:          "Ищу новую халяву прямо сейчас 🔄",
+	LastFetch:             "Последняя загрузка #%d в %s",
+	LastFetchError:        "Последняя ошибка, загрузка #%d в %s:\n%s",
+	NoFetches:             "Ещё ничего не загружалось",
+	NoFetchErrors:         "Ошибок загрузки не было 👌",
+	SourcesHeader:         "Источники, /mute <источник> чтобы выключить или включить его:",
+	MuteUsage:             "Напиши один источник после команды, например /mute pikabu",
+	SourceUnknown:         "Неизвестный источник \"%s\", есть такие: %s",
+	SourceMuted:           "%s выключен 🔇",
+	SourceUnmuted:         "%s снова загружается 🔊",
+	ChannelUnavailable:    "Канал %d не получает сообщения: %s\nХалява в него не отправляется до перезапуска бота ⚠️",
+
+	"weekday_0": "воскресенье",
+	"weekday_1": "понедельник",
+	"weekday_2": "вторник",
+	"weekday_3": "среда",
+	"weekday_4": "четверг",
+	"weekday_5": "пятница",
+	"weekday_6": "суббота",
+
+	"command_today":     "Халява за сегодня",
+	"command_yesterday": "Халява со вчерашнего дня",
+	"command_week":      "Халява за неделю",
+	"command_month":     "Халява за 30 дней",
+	"command_receive":   "Включить или выключить рассылку новой халявы",
+	"command_platforms": "Выбрать платформы",
+	"command_filter":    "Скрыть или оставить халяву по словам",
+	"command_batch":     "Получать новую халяву одним сообщением",
+	"command_digest":    "Получать новую халяву раз в день или в неделю",
+	"command_timezone":  "Установить часовой пояс",
+	"command_quiet":     "Установить тихие часы",
+	"command_language":  "Выбрать язык бота",
+	"command_claimed":   "Список забранной халявы",
+	"command_help":      "Список команд",
+	"command_search":    "Искать среди всей найденной халявы",
+
+	"command_stats":       "Показать, как работает бот",
+	"command_subscribers": "Показать подписчиков",
+	"command_broadcast":   "Отправить сообщение всем подписчикам",
+	"command_fetchnow":    "Загрузить новую халяву сейчас",
+	"command_lastfetch":   "Показать последнюю ошибку загрузки",
+	"command_mute":        "Выключить или включить источник",
+}
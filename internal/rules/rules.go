@@ -0,0 +1,273 @@
+package rules
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/freebies-telegram-bot/internal/fetchers"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	ActionAllow = "allow"
+	ActionDeny  = "deny"
+)
+
+var ruleMatches = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "game_freebies_rule_matches",
+	Help: "The number of links matched by each global rule",
+}, []string{"rule"})
+
+// Used when no rules file is configured
+//
+//go:embed default_rules.json
+var defaultRules []byte
+
+// Rule matches a link when all of its conditions match, empty conditions are ignored.
+type Rule struct {
+	Name string `json:"name"`
+	// Rules with higher priority are checked first, rules with the same priority
+	// in the order of the file
+	Priority int    `json:"priority"`
+	Action   string `json:"action"`
+
+	// Domains match the host of the link and its subdomains
+	Domains    []string `json:"domains"`
+	UrlRegex   string   `json:"url_regex"`
+	TitleRegex string   `json:"title_regex"`
+	Platforms  []string `json:"platforms"`
+	DealTypes  []string `json:"deal_types"`
+
+	urlRe   *regexp.Regexp
+	titleRe *regexp.Regexp
+}
+
+type Config struct {
+	// Action for the links matching no rule, allow by default
+	DefaultAction string `json:"default_action"`
+	Rules         []Rule `json:"rules"`
+}
+
+// Engine decides which links are delivered at all. Rules are loaded from
+// a JSON file and can be reloaded while the bot is running.
+type Engine struct {
+	path string
+
+	mu            sync.RWMutex
+	rules         []Rule
+	defaultAction string
+	modTime       time.Time
+}
+
+// NewEngine loads the rules from path, the default rules are used if path is empty.
+func NewEngine(path string) (*Engine, error) {
+	e := &Engine{path: path}
+	if err := e.Reload(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *Engine) Reload() error {
+	data := defaultRules
+	var modTime time.Time
+	if e.path != "" {
+		info, err := os.Stat(e.path)
+		if err != nil {
+			return fmt.Errorf("Unable to read rules file '%s': %w", e.path, err)
+		}
+		modTime = info.ModTime()
+
+		data, err = os.ReadFile(e.path)
+		if err != nil {
+			return fmt.Errorf("Unable to read rules file '%s': %w", e.path, err)
+		}
+	}
+
+	config, err := ParseConfig(data)
+	if err != nil {
+		return fmt.Errorf("Unable to load rules file '%s': %w", e.path, err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.rules = config.Rules
+	e.defaultAction = config.DefaultAction
+	e.modTime = modTime
+	log.Printf("Loaded %d link rules", len(config.Rules))
+	return nil
+}
+
+// Watch reloads the rules file every interval once it has changed. Broken
+// files are reported and the rules loaded before are kept.
+func (e *Engine) Watch(ctx context.Context, interval time.Duration) {
+	if e.path == "" {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(e.path)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		e.mu.RLock()
+		changed := !info.ModTime().Equal(e.modTime)
+		e.mu.RUnlock()
+		if !changed {
+			continue
+		}
+
+		if err := e.Reload(); err != nil {
+			log.Println(err)
+			// Don't retry until the file changes again
+			e.mu.Lock()
+			e.modTime = info.ModTime()
+			e.mu.Unlock()
+		}
+	}
+}
+
+// IsAllowed applies the first matching rule to the link. Stored links are
+// checked again on every read, so nothing is counted here.
+func (e *Engine) IsAllowed(link fetchers.Link) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if rule, ok := e.match(link); ok {
+		return rule.Action != ActionDeny
+	}
+	return e.defaultAction != ActionDeny
+}
+
+// CountMatches counts the links matched by each rule, it is given every
+// newly found link once
+func (e *Engine) CountMatches(links []fetchers.Link) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	for _, link := range links {
+		rule, ok := e.match(link)
+		if !ok {
+			continue
+		}
+		ruleMatches.WithLabelValues(rule.Name).Inc()
+		if rule.Action == ActionDeny {
+			log.Printf("Link %s is filtered by rule %s", link.Link, rule.Name)
+		}
+	}
+}
+
+// match returns the first rule matching the link
+func (e *Engine) match(link fetchers.Link) (Rule, bool) {
+	for _, rule := range e.rules {
+		if rule.Matches(link) {
+			return rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+func (r Rule) Matches(link fetchers.Link) bool {
+	if len(r.Domains) != 0 && !matchesDomain(link, r.Domains) {
+		return false
+	}
+	if r.urlRe != nil && !r.urlRe.MatchString(link.Link) {
+		return false
+	}
+	if r.titleRe != nil && !r.titleRe.MatchString(link.Title) {
+		return false
+	}
+	if len(r.Platforms) != 0 && !slices.Contains(r.Platforms, link.Platform) {
+		return false
+	}
+	if len(r.DealTypes) != 0 && !slices.Contains(r.DealTypes, link.DealType) {
+		return false
+	}
+	return true
+}
+
+func matchesDomain(link fetchers.Link, domains []string) bool {
+	u, err := url.Parse(link.Link)
+	if err != nil || u.Hostname() == "" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, domain := range domains {
+		domain = strings.ToLower(domain)
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseConfig validates the rules and sorts them by priority
+func ParseConfig(data []byte) (Config, error) {
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return Config{}, err
+	}
+
+	if config.DefaultAction == "" {
+		config.DefaultAction = ActionAllow
+	}
+	if config.DefaultAction != ActionAllow && config.DefaultAction != ActionDeny {
+		return Config{}, fmt.Errorf("unknown default action '%s'", config.DefaultAction)
+	}
+
+	names := map[string]bool{}
+	for i := range config.Rules {
+		rule := &config.Rules[i]
+		if rule.Name == "" {
+			return Config{}, fmt.Errorf("rule %d has no name", i+1)
+		}
+		if names[rule.Name] {
+			return Config{}, fmt.Errorf("rule '%s' is defined twice", rule.Name)
+		}
+		names[rule.Name] = true
+
+		if rule.Action != ActionAllow && rule.Action != ActionDeny {
+			return Config{}, fmt.Errorf("rule '%s' has unknown action '%s'", rule.Name, rule.Action)
+		}
+
+		var err error
+		if rule.UrlRegex != "" {
+			rule.urlRe, err = regexp.Compile(rule.UrlRegex)
+			if err != nil {
+				return Config{}, fmt.Errorf("rule '%s' has invalid url_regex: %w", rule.Name, err)
+			}
+		}
+		if rule.TitleRegex != "" {
+			rule.titleRe, err = regexp.Compile(rule.TitleRegex)
+			if err != nil {
+				return Config{}, fmt.Errorf("rule '%s' has invalid title_regex: %w", rule.Name, err)
+			}
+		}
+	}
+
+	sort.SliceStable(config.Rules, func(i, j int) bool {
+		return config.Rules[i].Priority > config.Rules[j].Priority
+	})
+	return config, nil
+}
@@ -0,0 +1,94 @@
+package rules
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/freebies-telegram-bot/internal/fetchers"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_DefaultRules(t *testing.T) {
+	engine, err := NewEngine("")
+	require.NoError(t, err)
+
+	assert.False(t, engine.IsAllowed(fetchers.Link{Link: "https://www.amazon.com/dp/B0"}))
+	assert.False(t, engine.IsAllowed(fetchers.Link{Link: "https://luna.amazon.com/claims/home"}))
+	assert.False(t, engine.IsAllowed(fetchers.Link{Link: "/r/FreeGameFindings/comments/1/"}))
+	assert.False(t, engine.IsAllowed(fetchers.Link{Link: "https://x.com/epicgames/status/1"}))
+	assert.True(t, engine.IsAllowed(fetchers.Link{Link: "https://store.steampowered.com/app/1/"}))
+}
+
+func Test_RulesPriority(t *testing.T) {
+	config, err := ParseConfig([]byte(`{
+		"default_action": "deny",
+		"rules": [
+			{"name": "deny_dlc", "action": "deny", "deal_types": ["dlc"]},
+			{"name": "allow_steam", "action": "allow", "platforms": ["steam"]},
+			{"name": "allow_gog_dlc", "priority": 10, "action": "allow", "domains": ["gog.com"], "title_regex": "(?i)\\(dlc\\)"}
+		]
+	}`))
+	require.NoError(t, err)
+	engine := &Engine{rules: config.Rules, defaultAction: config.DefaultAction}
+
+	assert.Equal(t, "allow_gog_dlc", config.Rules[0].Name)
+	assert.True(t, engine.IsAllowed(fetchers.Link{Link: "https://www.gog.com/game/1", Title: "[GOG] (DLC) One", DealType: fetchers.DealDLC}))
+	assert.False(t, engine.IsAllowed(fetchers.Link{Link: "https://store.steampowered.com/app/1", Platform: fetchers.PlatformSteam, DealType: fetchers.DealDLC}))
+	assert.True(t, engine.IsAllowed(fetchers.Link{Link: "https://store.steampowered.com/app/2", Platform: fetchers.PlatformSteam, DealType: fetchers.DealGame}))
+	assert.False(t, engine.IsAllowed(fetchers.Link{Link: "https://example.itch.io/game", Platform: fetchers.PlatformItch}))
+}
+
+func Test_ParseConfigErrors(t *testing.T) {
+	for _, config := range []string{
+		`{"rules": [{"action": "deny"}]}`,
+		`{"rules": [{"name": "a", "action": "drop"}]}`,
+		`{"rules": [{"name": "a", "action": "deny"}, {"name": "a", "action": "allow"}]}`,
+		`{"rules": [{"name": "a", "action": "deny", "url_regex": "("}]}`,
+		`{"default_action": "maybe"}`,
+	} {
+		_, err := ParseConfig([]byte(config))
+		assert.Error(t, err, config)
+	}
+}
+
+func Test_WatchReloadsRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"rules": []}`), 0644))
+
+	engine, err := NewEngine(path)
+	require.NoError(t, err)
+	link := fetchers.Link{Link: "https://store.epicgames.com/p/game"}
+	assert.True(t, engine.IsAllowed(link))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go engine.Watch(ctx, 10*time.Millisecond)
+
+	rules := []byte(`{"rules": [{"name": "skip_epic", "action": "deny", "domains": ["epicgames.com"]}]}`)
+	require.NoError(t, os.WriteFile(path, rules, 0644))
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Second)))
+
+	assert.Eventually(t, func() bool {
+		return !engine.IsAllowed(link)
+	}, time.Second, 10*time.Millisecond)
+}
+
+func Test_CountMatches(t *testing.T) {
+	config, err := ParseConfig([]byte(`{"rules": [{"name": "count_deny_itch", "action": "deny", "platforms": ["itch"]}]}`))
+	require.NoError(t, err)
+	engine := &Engine{rules: config.Rules, defaultAction: config.DefaultAction}
+	matches := ruleMatches.WithLabelValues("count_deny_itch")
+	itch := fetchers.Link{Link: "https://example.itch.io/game", Platform: fetchers.PlatformItch}
+
+	assert.False(t, engine.IsAllowed(itch))
+	assert.False(t, engine.IsAllowed(itch))
+	assert.Equal(t, 0.0, testutil.ToFloat64(matches))
+
+	engine.CountMatches([]fetchers.Link{itch, {Link: "https://store.steampowered.com/app/1/", Platform: fetchers.PlatformSteam}})
+	assert.Equal(t, 1.0, testutil.ToFloat64(matches))
+}
@@ -0,0 +1,54 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-co-op/gocron/v2"
+	"github.com/go-faster/errors"
+)
+
+type DigestSender interface {
+	SendDueDigests(now time.Time)
+}
+
+// DigestScheduler checks regularly for the subscribers whose digest is due
+type DigestScheduler struct {
+	scheduler gocron.Scheduler
+	sender    DigestSender
+}
+
+func NewDigestScheduler(sender DigestSender) (DigestScheduler, error) {
+	s, err := gocron.NewScheduler()
+	if err != nil {
+		return DigestScheduler{}, errors.Wrap(err, "failed to create cron scheduler")
+	}
+	return DigestScheduler{s, sender}, nil
+}
+
+func (ds DigestScheduler) Start(cronSchedule string) error {
+	_, err := ds.scheduler.NewJob(
+		gocron.CronJob(cronSchedule, true),
+		gocron.NewTask(ds.send),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to create job ")
+	}
+
+	ds.scheduler.Start()
+
+	return nil
+}
+
+func (ds DigestScheduler) Stop(ctx context.Context) error {
+	err := ds.scheduler.ShutdownWithContext(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to shutdown cron scheduler")
+	}
+	return nil
+}
+
+func (ds DigestScheduler) send() {
+	ds.sender.SendDueDigests(time.Now().UTC())
+}
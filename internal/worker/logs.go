@@ -15,6 +15,7 @@ type LogsStorage interface {
 	DeleteFetchesOlderThan(deadline time.Time) (int64, error)
 	DeletePostsOlderThan(deadline time.Time) (int64, error)
 	DeleteDeliveredPostsOlderThan(deadline time.Time) (int64, error)
+	DeleteOutboxOlderThan(deadline time.Time) (int64, error)
 }
 
 type LogsCleaner struct {
@@ -75,4 +76,11 @@ func (lc LogsCleaner) clean() {
 	}
 	log.Printf("[LogsCleaner] cleaned %d delivered posts logs\n", deliveredPostsCleaned)
 
+	log.Printf("[LogsCleaner] cleaning outbox older than %s\n", deadline)
+	outboxCleaned, err := lc.db.DeleteOutboxOlderThan(deadline)
+	if err != nil {
+		log.Printf("failed clean outbox: %s", err.Error())
+	}
+	log.Printf("[LogsCleaner] cleaned %d outbox messages\n", outboxCleaned)
+
 }